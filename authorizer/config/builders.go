@@ -0,0 +1,519 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"nuledger/authorizer/rule"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"sort"
+	"time"
+)
+
+// builder creates the authorizer of a rule type from its raw JSON params,
+// collecting the violations defined by the rule in `defs`.
+type builder func(params json.RawMessage, defs *violationDefs) (rule.Authorizer, error)
+
+// builders are all the rule types that can be configured, by their names.
+var builders = map[string]builder{
+	"chronological-order": withoutParams(func() rule.Authorizer { return &rules.ChronologicalOrder{} }),
+	"account-card-active": withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.AccountCardActive) }),
+	"sufficient-limit":    withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.SufficientLimit) }),
+	"merchant-lists":      withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.MerchantLists) }),
+	"country-lists":       withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.CountryLists) }),
+	"allowed-schedule":    withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.AllowedSchedule) }),
+	"subscription-amount": withoutParams(func() rule.Authorizer { return rule.AuthorizerFunc(rules.SubscriptionAmount) }),
+	"category-controls":   withoutParams(func() rule.Authorizer { return rules.NewCategoryControls() }),
+
+	"overdraft":            buildOverdraft,
+	"limited-frequency":    buildLimitedFrequency,
+	"multi-tier-frequency": buildMultiTierFrequency,
+	"calendar-frequency":   buildCalendarFrequency,
+	"spend-limit":          buildSpendLimit,
+	"calendar-spend-limit": buildCalendarSpendLimit,
+	"unique-transactions":  buildUniqueTransactions,
+	"fuzzy-duplicates":     buildFuzzyDuplicates,
+	"merchant-quarantine":  buildMerchantQuarantine,
+	"impossible-travel":    buildImpossibleTravel,
+	"risk-score":           buildRiskScore,
+	"amount-anomaly":       buildAmountAnomaly,
+	"card-testing":         buildCardTesting,
+	"expression":           buildExpression,
+}
+
+// The rule types wrapping other rules are only registered on init, since their
+// builders depend on the builders of all the other rule types.
+func init() {
+	builders["shadow"] = buildShadow
+	builders["rollout"] = buildRollout
+}
+
+// ruleTypes returns the sorted names of all the rule types that can be
+// configured.
+func ruleTypes() []string {
+	names := make([]string, 0, len(builders))
+	for name := range builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func withoutParams(newAuthorizer func() rule.Authorizer) builder {
+	return func(params json.RawMessage, _ *violationDefs) (rule.Authorizer, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return newAuthorizer(), nil
+	}
+}
+
+func buildOverdraft(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Fee int64 `json:"fee"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Fee < 0 {
+		return nil, fmt.Errorf("fee must not be negative")
+	}
+	return rules.NewOverdraft(params.Fee), nil
+}
+
+type frequencyParams struct {
+	MaxTransactions int      `json:"max-transactions"`
+	Interval        Duration `json:"interval"`
+}
+
+func (p frequencyParams) validate() error {
+	return firstError(
+		requirePositive("max-transactions", float64(p.MaxTransactions)),
+		requirePositive("interval", float64(p.Interval)))
+}
+
+func buildLimitedFrequency(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params frequencyParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return rules.NewLimitedFrequency(params.MaxTransactions, time.Duration(params.Interval)), nil
+}
+
+func buildMultiTierFrequency(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Tiers []frequencyParams `json:"tiers"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if len(params.Tiers) == 0 {
+		return nil, fmt.Errorf("tiers must not be empty")
+	}
+	tiers := make([]util.RateTier, len(params.Tiers))
+	for i, tier := range params.Tiers {
+		if err := tier.validate(); err != nil {
+			return nil, fmt.Errorf("tier %d: %w", i, err)
+		}
+		tiers[i] = util.RateTier{MaxEvents: tier.MaxTransactions, Interval: time.Duration(tier.Interval)}
+	}
+	return rules.NewMultiTierFrequency(tiers...), nil
+}
+
+func buildCalendarFrequency(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxTransactions int                  `json:"max-transactions"`
+		Window          *util.CalendarWindow `json:"window"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := firstError(
+		requirePositive("max-transactions", float64(params.MaxTransactions)),
+		requireWindow(params.Window)); err != nil {
+		return nil, err
+	}
+	return rules.NewCalendarFrequency(params.MaxTransactions, *params.Window), nil
+}
+
+func buildSpendLimit(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxAmount int64    `json:"max-amount"`
+		Interval  Duration `json:"interval"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := firstError(
+		requirePositive("max-amount", float64(params.MaxAmount)),
+		requirePositive("interval", float64(params.Interval))); err != nil {
+		return nil, err
+	}
+	return rules.NewSpendLimit(params.MaxAmount, time.Duration(params.Interval)), nil
+}
+
+func buildCalendarSpendLimit(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxAmount int64                `json:"max-amount"`
+		Window    *util.CalendarWindow `json:"window"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := firstError(
+		requirePositive("max-amount", float64(params.MaxAmount)),
+		requireWindow(params.Window)); err != nil {
+		return nil, err
+	}
+	return rules.NewCalendarSpendLimit(params.MaxAmount, *params.Window), nil
+}
+
+func buildUniqueTransactions(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Interval          Duration                    `json:"interval"`
+		MerchantIntervals []merchantIntervalParams    `json:"merchant-intervals"`
+		CategoryIntervals map[model.Category]Duration `json:"category-intervals"`
+		ExemptMerchants   []string                    `json:"exempt-merchants"`
+		ExemptCategories  []model.Category            `json:"exempt-categories"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("interval", float64(params.Interval)); err != nil {
+		return nil, err
+	}
+
+	config := rules.UniqueTransactionsConfig{
+		Interval:          time.Duration(params.Interval),
+		CategoryIntervals: map[model.Category]time.Duration{},
+		ExemptMerchants:   params.ExemptMerchants,
+		ExemptCategories:  params.ExemptCategories,
+	}
+	for i, override := range params.MerchantIntervals {
+		if override.Merchant == "" {
+			return nil, fmt.Errorf("merchant interval %d: merchant must not be empty", i)
+		}
+		if err := requirePositive("interval", float64(override.Interval)); err != nil {
+			return nil, fmt.Errorf("merchant interval %d: %w", i, err)
+		}
+		config.MerchantIntervals = append(config.MerchantIntervals,
+			rules.MerchantInterval{Merchant: override.Merchant, Interval: time.Duration(override.Interval)})
+	}
+	for category, interval := range params.CategoryIntervals {
+		if err := requirePositive("interval", float64(interval)); err != nil {
+			return nil, fmt.Errorf("category interval %q: %w", category, err)
+		}
+		config.CategoryIntervals[category] = time.Duration(interval)
+	}
+	return rules.NewUniqueTransactions(config), nil
+}
+
+type merchantIntervalParams struct {
+	Merchant string   `json:"merchant"`
+	Interval Duration `json:"interval"`
+}
+
+func buildFuzzyDuplicates(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Interval            Duration `json:"interval"`
+		AbsoluteTolerance   int64    `json:"absolute-tolerance"`
+		PercentageTolerance float64  `json:"percentage-tolerance"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("interval", float64(params.Interval)); err != nil {
+		return nil, err
+	}
+	if params.AbsoluteTolerance < 0 || params.PercentageTolerance < 0 {
+		return nil, fmt.Errorf("tolerances must not be negative")
+	}
+	tolerance := rules.AmountTolerance{Absolute: params.AbsoluteTolerance, Percentage: params.PercentageTolerance}
+	return rules.NewFuzzyDuplicates(time.Duration(params.Interval), tolerance), nil
+}
+
+func buildMerchantQuarantine(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		frequencyParams
+		Duration Duration `json:"duration"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := firstError(
+		params.validate(),
+		requirePositive("duration", float64(params.Duration))); err != nil {
+		return nil, err
+	}
+	return rules.NewMerchantQuarantine(params.MaxTransactions, time.Duration(params.Interval), time.Duration(params.Duration)), nil
+}
+
+func buildImpossibleTravel(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxSpeedKmh float64 `json:"max-speed-kmh"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("max-speed-kmh", params.MaxSpeedKmh); err != nil {
+		return nil, err
+	}
+	return rules.NewImpossibleTravel(params.MaxSpeedKmh), nil
+}
+
+// riskSignals are all the known risk signals, for validating the weights.
+var riskSignals = []model.RiskSignal{
+	model.RiskSignalNewMerchant,
+	model.RiskSignalUnusualAmount,
+	model.RiskSignalHighVelocity,
+	model.RiskSignalNightTime,
+	model.RiskSignalForeignCountry,
+}
+
+func buildRiskScore(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Weights   map[model.RiskSignal]float64 `json:"weights"`
+		Threshold float64                      `json:"threshold"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if len(params.Weights) == 0 {
+		return nil, fmt.Errorf("weights must not be empty")
+	}
+	if err := requirePositive("threshold", params.Threshold); err != nil {
+		return nil, err
+	}
+	for signal := range params.Weights {
+		if !containsSignal(riskSignals, signal) {
+			return nil, fmt.Errorf("unknown risk signal %q", signal)
+		}
+	}
+	return rules.NewRiskScore(params.Weights, params.Threshold), nil
+}
+
+func buildAmountAnomaly(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxDeviations float64 `json:"max-deviations"`
+		WarmUp        int     `json:"warm-up"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("max-deviations", params.MaxDeviations); err != nil {
+		return nil, err
+	}
+	if params.WarmUp < 2 {
+		return nil, fmt.Errorf("warm-up must be at least 2 transactions")
+	}
+	return rules.NewAmountAnomaly(params.MaxDeviations, params.WarmUp), nil
+}
+
+func buildCardTesting(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		MaxAmount    int64    `json:"max-amount"`
+		MaxMerchants int      `json:"max-merchants"`
+		Window       Duration `json:"window"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := firstError(
+		requirePositive("max-amount", float64(params.MaxAmount)),
+		requirePositive("max-merchants", float64(params.MaxMerchants)),
+		requirePositive("window", float64(params.Window))); err != nil {
+		return nil, err
+	}
+	return rules.NewCardTesting(params.MaxAmount, params.MaxMerchants, time.Duration(params.Window)), nil
+}
+
+func buildExpression(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Expression string             `json:"expression"`
+		Violation  violation.Code     `json:"violation"`
+		Message    string             `json:"message"`
+		Severity   violation.Severity `json:"severity"`
+		Retriable  bool               `json:"retriable"`
+		// Messages are the localized messages of the violation by locale.
+		Messages map[violation.Locale]string `json:"messages"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Violation == "" {
+		return nil, fmt.Errorf("violation must not be empty")
+	}
+	if params.Message == "" {
+		params.Message = fmt.Sprintf("Transaction matched the rule: %s", params.Expression)
+	}
+
+	authzer, err := rules.NewExpression(params.Expression, violation.NewError(params.Violation, "%s", params.Message))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	if params.Severity == "" {
+		params.Severity = violation.SeverityMedium
+	}
+	def := violation.Definition{Code: params.Violation, Severity: params.Severity, Description: params.Message, Retriable: params.Retriable}
+	if err := defs.add(def, params.Messages); err != nil {
+		return nil, err
+	}
+	return authzer, nil
+}
+
+// violationDefs collects the definitions and localized messages of the
+// violations defined in a configuration, so that they are only registered
+// after all of its rules are built.
+type violationDefs struct {
+	defs     map[violation.Code]violation.Definition
+	messages map[violation.Locale]map[violation.Code]string
+}
+
+func newViolationDefs() *violationDefs {
+	return &violationDefs{
+		defs:     map[violation.Code]violation.Definition{},
+		messages: map[violation.Locale]map[violation.Code]string{},
+	}
+}
+
+// add collects the definition of a violation with its localized messages. It
+// returns an error if the violation has already been defined differently in
+// the configuration.
+func (v *violationDefs) add(def violation.Definition, messages map[violation.Locale]string) error {
+	if existing, ok := v.defs[def.Code]; ok && existing != def {
+		return fmt.Errorf("Violation %s is already defined with another definition", def.Code)
+	}
+	v.defs[def.Code] = def
+	for locale, message := range messages {
+		if v.messages[locale] == nil {
+			v.messages[locale] = map[violation.Code]string{}
+		}
+		v.messages[locale][def.Code] = message
+	}
+	return nil
+}
+
+// register registers all the collected definitions and messages, returning
+// an error if any of them is invalid or conflicts with an already registered
+// definition (e.g. of the violations of the built-in rules).
+func (v *violationDefs) register() error {
+	codes := make([]string, 0, len(v.defs))
+	for code := range v.defs {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if err := violation.Register(v.defs[violation.Code(code)]); err != nil {
+			return err
+		}
+	}
+	for locale, messages := range v.messages {
+		if err := violation.RegisterMessages(locale, messages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildShadow(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Name string     `json:"name"`
+		Rule RuleConfig `json:"rule"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Name == "" {
+		params.Name = params.Rule.name()
+	}
+
+	authzer, err := params.Rule.build(defs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+	return rule.NewShadow(params.Name, authzer), nil
+}
+
+func buildRollout(raw json.RawMessage, defs *violationDefs) (rule.Authorizer, error) {
+	var params struct {
+		Name       string      `json:"name"`
+		Percentage float64     `json:"percentage"`
+		Rule       RuleConfig  `json:"rule"`
+		Control    *RuleConfig `json:"control"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	if params.Percentage < 0 || params.Percentage > 100 {
+		return nil, fmt.Errorf("percentage must be between 0 and 100")
+	}
+
+	treatment, err := params.Rule.build(defs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+	var control rule.Authorizer
+	if params.Control != nil {
+		if control, err = params.Control.build(defs); err != nil {
+			return nil, fmt.Errorf("invalid control: %w", err)
+		}
+	}
+	return rule.NewRollout(params.Name, params.Percentage, treatment, control), nil
+}
+
+// decodeParams decodes the raw JSON params into the given destination,
+// returning an error for any unknown fields. Missing params are left with the
+// zero values of the destination.
+func decodeParams(raw json.RawMessage, dest interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+func requirePositive(name string, value float64) error {
+	if value <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	return nil
+}
+
+func requireWindow(window *util.CalendarWindow) error {
+	if window == nil {
+		return fmt.Errorf("window must be one of \"day\", \"week\" or \"month\"")
+	}
+	return nil
+}
+
+func containsSignal(signals []model.RiskSignal, signal model.RiskSignal) bool {
+	for _, elm := range signals {
+		if elm == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// firstError returns the first non-nil error of the given ones, if any.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package config implements a declarative configuration format for the rules
+// used to authorize transactions, so that they can be chosen and tuned without
+// changing the code of the application.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"nuledger/authorizer/rule"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config is the root object of a configuration file, in the JSON format. It
+// has the list of rules to be validated for every transaction, in order, e.g.:
+//
+//	{"rules": [
+//	  {"type": "account-card-active"},
+//	  {"type": "limited-frequency", "params": {"max-transactions": 3, "interval": "2m"}}
+//	]}
+//
+// The rule types without params are chronological-order, account-card-active,
+// sufficient-limit, merchant-lists, country-lists, allowed-schedule,
+// subscription-amount and category-controls. The ones with params are:
+//
+//	overdraft             fee
+//	limited-frequency     max-transactions, interval
+//	multi-tier-frequency  tiers (each with max-transactions and interval)
+//	calendar-frequency    max-transactions, window
+//	spend-limit           max-amount, interval
+//	calendar-spend-limit  max-amount, window
+//	unique-transactions   interval, merchant-intervals (each with merchant and
+//	                      interval), category-intervals, exempt-merchants,
+//	                      exempt-categories
+//	fuzzy-duplicates      interval, absolute-tolerance, percentage-tolerance
+//	merchant-quarantine   max-transactions, interval, duration
+//	impossible-travel     max-speed-kmh
+//	risk-score            weights (by risk signal), threshold
+//	amount-anomaly        max-deviations, warm-up
+//	card-testing          max-amount, max-merchants, window
+//	expression            expression, violation, message (optional)
+//	shadow                rule (any rule config), name (optional)
+//	rollout               name, percentage, rule, control (optional rule)
+//
+// Each of them corresponds to the authorizer with the same name in the rules
+// package, with params analogous to the arguments of its constructor. Any rule
+// can also have a `name` to identify it in the traces instead of its type,
+// except for the shadow and rollout rules, which have it in their params.
+//
+// The configuration can also have the lists of rules of each account profile
+// (e.g. product tiers), used instead of the default rules for the accounts with
+// that profile:
+//
+//	{"rules": [...], "profiles": {
+//	  "premium": [{"type": "account-card-active"}, {"type": "overdraft", "params": {"fee": 0}}]
+//	}}
+type Config struct {
+	Rules    []RuleConfig            `json:"rules"`
+	Profiles map[string][]RuleConfig `json:"profiles,omitempty"`
+}
+
+// RuleConfig is the configuration of a single rule, with the name of its
+// `type`, the parameters specific to that type, if any, and an optional `name`
+// to identify the rule in the traces, which defaults to its type.
+type RuleConfig struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Duration is a time.Duration represented in JSON as a string in the format
+// accepted by time.ParseDuration (e.g. "2m" or "1h30m").
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface, parsing the string
+// representation of the duration.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("Duration must be a string like \"2m\": %w", err)
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Load reads a configuration from the given reader, returning an error if it
+// is not a valid JSON or has unknown fields. The rules themselves are only
+// validated when building the authorizer.
+func Load(r io.Reader) (Config, error) {
+	var config Config
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("Invalid configuration: %w", err)
+	}
+	return config, nil
+}
+
+// LoadFile loads the configuration from the file at the given path, analogously
+// to Load.
+func LoadFile(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Build creates the authorizer with all the configured rules, in the same
+// order as they are configured. It returns an error describing the first
+// invalid rule found, if any.
+func (c Config) Build() (rule.List, error) {
+	defs := newViolationDefs()
+	list, err := buildList(c.Rules, defs)
+	if err == nil {
+		err = defs.register()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid configuration: %w", err)
+	}
+	return list, nil
+}
+
+// BuildProfiles creates the authorizers of all the configured profiles, by
+// their names, analogously to Build. It returns nil if there are no profiles.
+func (c Config) BuildProfiles() (map[string]rule.Authorizer, error) {
+	if len(c.Profiles) == 0 {
+		return nil, nil
+	}
+
+	defs := newViolationDefs()
+	profiles := make(map[string]rule.Authorizer, len(c.Profiles))
+	for name, rules := range c.Profiles {
+		if name == "" {
+			return nil, errors.New("Invalid configuration: profile names must not be empty")
+		}
+		list, err := buildList(rules, defs)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid configuration of profile %q: %w", name, err)
+		}
+		profiles[name] = list
+	}
+	if err := defs.register(); err != nil {
+		return nil, fmt.Errorf("Invalid configuration of profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// ProfileNames returns the sorted names of the configured profiles.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildList builds the authorizers of a list of rules, analogously to Build,
+// collecting the violations defined by them in `defs`.
+func buildList(rules []RuleConfig, defs *violationDefs) (rule.List, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("at least one rule must be configured")
+	}
+
+	list := make(rule.List, 0, len(rules))
+	for i, ruleConfig := range rules {
+		authzer, err := ruleConfig.build(defs)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		list = append(list, authzer)
+	}
+	return list, nil
+}
+
+// Build creates the authorizer of the configured rule type with its params,
+// returning an error if the type is unknown or the params are not valid. The
+// authorizer is wrapped in a rule.Named with the name of the rule, unless it
+// has its own name param (i.e. shadow and rollout rules). The violations
+// defined by the rule are only registered if it is valid.
+func (r RuleConfig) Build() (rule.Authorizer, error) {
+	defs := newViolationDefs()
+	authzer, err := r.build(defs)
+	if err != nil {
+		return nil, err
+	}
+	if err := defs.register(); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Type, err)
+	}
+	return authzer, nil
+}
+
+// build implements Build, collecting the violations defined by the rule in
+// `defs` instead of registering them.
+func (r RuleConfig) build(defs *violationDefs) (rule.Authorizer, error) {
+	builder, ok := builders[r.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown rule type %q, must be one of: %s", r.Type, strings.Join(ruleTypes(), ", "))
+	}
+	authzer, err := builder(r.Params, defs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Type, err)
+	}
+	if _, ok := authzer.(rule.RuleNamer); ok {
+		if r.Name != "" {
+			return nil, fmt.Errorf("%s: name must be configured in the params", r.Type)
+		}
+		return authzer, nil
+	}
+	return rule.Named{Name: r.name(), Authorizer: authzer}, nil
+}
+
+// name returns the name of the configured rule, which defaults to its type.
+func (r RuleConfig) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Type
+}
@@ -0,0 +1,292 @@
+package config_test
+
+import (
+	"errors"
+	"nuledger/authorizer/config"
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var startTime = time.Date(2021, time.April, 13, 10, 0, 0, 0, time.UTC)
+
+func TestLoad(t *testing.T) {
+	Convey("Given a configuration JSON", t, func() {
+		Convey("It should load its rules", func() {
+			cfg, err := config.Load(strings.NewReader(`{"rules": [{"type": "sufficient-limit"}, {"type": "overdraft", "params": {"fee": 5}}]}`))
+			So(err, ShouldBeNil)
+			So(cfg.Rules, ShouldHaveLength, 2)
+			So(cfg.Rules[0].Type, ShouldEqual, "sufficient-limit")
+			So(string(cfg.Rules[1].Params), ShouldEqual, `{"fee": 5}`)
+		})
+		Convey("It should return an error for invalid JSON", func() {
+			_, err := config.Load(strings.NewReader(`{"rules": [`))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("It should return an error for unknown fields", func() {
+			_, err := config.Load(strings.NewReader(`{"rulez": []}`))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestBuild(t *testing.T) {
+	Convey("Given a rules configuration", t, func() {
+		build := func(rules ...config.RuleConfig) (rule.List, error) {
+			return config.Config{Rules: rules}.Build()
+		}
+		ruleConfig := func(ruleType, params string) config.RuleConfig {
+			return config.RuleConfig{Type: ruleType, Params: []byte(params)}
+		}
+
+		Convey("It should build the configured rules in order", func() {
+			list, err := build(
+				ruleConfig("account-card-active", ""),
+				ruleConfig("limited-frequency", `{"max-transactions": 1, "interval": "1m"}`),
+			)
+			So(err, ShouldBeNil)
+			So(list, ShouldHaveLength, 2)
+
+			account := model.Account{ActiveCard: true, AvailableLimit: 100}
+			transaction := model.Transaction{Merchant: "Bakery", Amount: 10, Time: startTime}
+			commitFunc, err := list.Authorize(account, transaction)
+			So(err, ShouldBeNil)
+			commitFunc(&account)
+
+			transaction.Time = startTime.Add(30 * time.Second)
+			_, err = list.Authorize(account, transaction)
+			So(errors.Is(err, violation.ErrorHighFrequencySmallInterval), ShouldBeTrue)
+		})
+
+		Convey("It should name the configured rules in the traces", func() {
+			named := ruleConfig("account-card-active", "")
+			named.Name = "card-check"
+			list, err := build(
+				ruleConfig("limited-frequency", `{"max-transactions": 1, "interval": "1m"}`),
+				named,
+				ruleConfig("shadow", `{"rule": {"type": "sufficient-limit", "name": "limit-check"}}`),
+			)
+			So(err, ShouldBeNil)
+
+			trace := &rule.Trace{}
+			account := model.Account{ActiveCard: true, AvailableLimit: 100}
+			transaction := model.Transaction{Merchant: "Bakery", Amount: 10, Time: startTime}
+			_, err = rule.AuthorizeTraced(list, account, transaction, trace)
+			So(err, ShouldBeNil)
+			So(trace.Decisions, ShouldHaveLength, 3)
+			So(trace.Decisions[0].Rule, ShouldEqual, "limited-frequency")
+			So(trace.Decisions[1].Rule, ShouldEqual, "card-check")
+			So(trace.Decisions[2].Rule, ShouldEqual, "limit-check")
+		})
+
+		Convey("It should build the default rules from the default configuration", func() {
+			list, err := config.Default().Build()
+			So(err, ShouldBeNil)
+			So(list, ShouldHaveLength, 10)
+
+			account := model.Account{ActiveCard: true, AvailableLimit: 100}
+			perform := func(merchant string, diff time.Duration) error {
+				transaction := model.Transaction{Merchant: merchant, Amount: 10, Time: startTime.Add(diff)}
+				commitFunc, err := list.Authorize(account, transaction)
+				if err == nil {
+					account.AvailableLimit -= transaction.Amount
+					commitFunc(&account)
+				}
+				return err
+			}
+
+			So(perform("Bakery", 0), ShouldBeNil)
+			So(errors.Is(perform("Bakery", time.Minute), violation.ErrorDoubleTransaction), ShouldBeTrue)
+			So(perform("Grocery", time.Minute), ShouldBeNil)
+			So(perform("Pharmacy", time.Minute), ShouldBeNil)
+			So(errors.Is(perform("Bookstore", time.Minute), violation.ErrorHighFrequencySmallInterval), ShouldBeTrue)
+			So(perform("Pharmacy", 10*time.Minute), ShouldBeNil)
+
+			account.ActiveCard = false
+			So(errors.Is(perform("Bookstore", 11*time.Minute), violation.ErrorCardNotActive), ShouldBeTrue)
+		})
+
+		Convey("It should return clear errors", func() {
+			testError := func(expected string, rules ...config.RuleConfig) {
+				_, err := build(rules...)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, expected)
+			}
+
+			Convey("For no rules", func() {
+				testError("at least one rule")
+			})
+			Convey("For unknown rule types", func() {
+				testError(`rule 1: Unknown rule type "magic"`, ruleConfig("sufficient-limit", ""), ruleConfig("magic", ""))
+			})
+			Convey("For unknown params", func() {
+				testError("sufficient-limit: invalid params", ruleConfig("sufficient-limit", `{"limit": 1}`))
+				testError("limited-frequency: invalid params", ruleConfig("limited-frequency", `{"max-transactions": 1, "interval": "1m", "burst": 2}`))
+			})
+			Convey("For invalid params", func() {
+				testError("max-transactions must be positive", ruleConfig("limited-frequency", `{"interval": "1m"}`))
+				testError("invalid duration", ruleConfig("spend-limit", `{"max-amount": 1, "interval": "one minute"}`))
+				testError("window must be one of", ruleConfig("calendar-frequency", `{"max-transactions": 1}`))
+				testError("Unknown calendar window", ruleConfig("calendar-spend-limit", `{"max-amount": 1, "window": "year"}`))
+				testError("tier 1: interval must be positive", ruleConfig("multi-tier-frequency",
+					`{"tiers": [{"max-transactions": 1, "interval": "1m"}, {"max-transactions": 5}]}`))
+				testError(`unknown risk signal "full-moon"`, ruleConfig("risk-score", `{"weights": {"full-moon": 10}, "threshold": 5}`))
+				testError("threshold must be positive", ruleConfig("risk-score", `{"weights": {"new-merchant": 10}}`))
+				testError("invalid expression: at position 9: unexpected end of expression", ruleConfig("expression",
+					`{"expression": "amount > ", "violation": "custom"}`))
+				testError("violation must not be empty", ruleConfig("expression", `{"expression": "amount > 1"}`))
+				testError(`Unknown severity of violation custom: "fatal"`, ruleConfig("expression",
+					`{"expression": "amount > 1", "violation": "custom", "severity": "fatal"}`))
+				testError("Invalid pt message of violation custom", ruleConfig("expression",
+					`{"expression": "amount > 1", "violation": "custom", "messages": {"pt": "{{.amount"}}`))
+				testError(`shadow: invalid rule: Unknown rule type "magic"`, ruleConfig("shadow", `{"rule": {"type": "magic"}}`))
+				namedShadow := ruleConfig("shadow", `{"rule": {"type": "sufficient-limit"}}`)
+				namedShadow.Name = "shadowed"
+				testError("shadow: name must be configured in the params", namedShadow)
+				testError("percentage must be between 0 and 100", ruleConfig("rollout",
+					`{"name": "ramp", "percentage": 101, "rule": {"type": "sufficient-limit"}}`))
+				testError("invalid control: overdraft: fee must not be negative", ruleConfig("rollout",
+					`{"name": "ramp", "percentage": 10, "rule": {"type": "sufficient-limit"}, "control": {"type": "overdraft", "params": {"fee": -1}}}`))
+				testError("merchant interval 0: merchant must not be empty", ruleConfig("unique-transactions",
+					`{"interval": "2m", "merchant-intervals": [{"interval": "1m"}]}`))
+			})
+		})
+
+		Convey("It should register the violations of expression rules", func() {
+			_, err := build(ruleConfig("expression",
+				`{"expression": "amount > 500", "violation": "big-spender", "message": "Too big", "severity": "high", "retriable": true}`))
+			So(err, ShouldBeNil)
+
+			def, ok := violation.Lookup("big-spender")
+			So(ok, ShouldBeTrue)
+			So(def, ShouldResemble, violation.Definition{
+				Code:        "big-spender",
+				Severity:    violation.SeverityHigh,
+				Description: "Too big",
+				Retriable:   true,
+			})
+
+			Convey("With their localized messages", func() {
+				_, err := build(ruleConfig("expression",
+					`{"expression": "amount > 500", "violation": "big-spender", "message": "Too big", "severity": "high", "retriable": true, "messages": {"pt": "Gasto grande demais", "es": "Gasto demasiado grande"}}`))
+				So(err, ShouldBeNil)
+
+				verr := violation.NewError("big-spender", "Too big")
+				So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual, "Gasto grande demais")
+				So(violation.Localize(verr, violation.LocaleSpanish), ShouldEqual, "Gasto demasiado grande")
+				So(violation.Localize(verr, violation.LocaleEnglish), ShouldEqual, "Too big")
+			})
+
+			Convey("Returning an error for codes already registered with another definition", func() {
+				_, err := build(ruleConfig("expression", `{"expression": "amount > 50", "violation": "insufficient-limit"}`))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "Violation insufficient-limit is already registered with another definition")
+
+				def, _ := violation.Lookup(violation.InsufficientLimit)
+				So(def.Description, ShouldEqual, violation.ErrorInsufficientLimit.Message)
+			})
+		})
+
+		Convey("It should only register the violations of valid configurations", func() {
+			_, err := build(
+				ruleConfig("expression", `{"expression": "amount > 500", "violation": "never-registered"}`),
+				ruleConfig("magic", ""),
+			)
+			So(err, ShouldNotBeNil)
+
+			_, ok := violation.Lookup("never-registered")
+			So(ok, ShouldBeFalse)
+
+			Convey("Returning an error for codes defined differently in the same configuration", func() {
+				_, err := build(
+					ruleConfig("expression", `{"expression": "amount > 500", "violation": "never-registered", "message": "Too big"}`),
+					ruleConfig("expression", `{"expression": "amount > 900", "violation": "never-registered", "message": "Way too big"}`),
+				)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "rule 1: expression: Violation never-registered is already defined with another definition")
+
+				_, ok := violation.Lookup("never-registered")
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("It should build every rule type with valid params", func() {
+			list, err := build(
+				ruleConfig("chronological-order", "{}"),
+				ruleConfig("merchant-lists", ""),
+				ruleConfig("country-lists", ""),
+				ruleConfig("allowed-schedule", ""),
+				ruleConfig("subscription-amount", ""),
+				ruleConfig("category-controls", ""),
+				ruleConfig("overdraft", `{"fee": 2}`),
+				ruleConfig("multi-tier-frequency", `{"tiers": [{"max-transactions": 3, "interval": "2m"}, {"max-transactions": 10, "interval": "1h"}]}`),
+				ruleConfig("calendar-frequency", `{"max-transactions": 5, "window": "day"}`),
+				ruleConfig("spend-limit", `{"max-amount": 500, "interval": "24h"}`),
+				ruleConfig("calendar-spend-limit", `{"max-amount": 5000, "window": "month"}`),
+				ruleConfig("unique-transactions", `{"interval": "2m", "merchant-intervals": [{"merchant": "Metro*", "interval": "10s"}], "category-intervals": {"groceries": "5m"}, "exempt-merchants": ["Bus*"], "exempt-categories": ["fuel"]}`),
+				ruleConfig("fuzzy-duplicates", `{"interval": "2m", "absolute-tolerance": 1, "percentage-tolerance": 1.5}`),
+				ruleConfig("merchant-quarantine", `{"max-transactions": 100, "interval": "1m", "duration": "1h"}`),
+				ruleConfig("impossible-travel", `{"max-speed-kmh": 900}`),
+				ruleConfig("risk-score", `{"weights": {"new-merchant": 10, "night-time": 20}, "threshold": 25}`),
+				ruleConfig("amount-anomaly", `{"max-deviations": 3, "warm-up": 5}`),
+				ruleConfig("card-testing", `{"max-amount": 1, "max-merchants": 3, "window": "10m"}`),
+				ruleConfig("expression", `{"expression": "amount > 500 && merchant startsWith \"CASINO\"", "violation": "casino-limit"}`),
+				ruleConfig("shadow", `{"name": "stricter-frequency", "rule": {"type": "limited-frequency", "params": {"max-transactions": 1, "interval": "1m"}}}`),
+				ruleConfig("rollout", `{"name": "new-overdraft", "percentage": 10, "rule": {"type": "overdraft", "params": {"fee": 1}}, "control": {"type": "sufficient-limit"}}`),
+			)
+			So(err, ShouldBeNil)
+			So(list, ShouldHaveLength, 21)
+			So(rule.CollectMetrics(list), ShouldResemble, []rule.Metrics{
+				{Rule: "stricter-frequency"},
+				{Rule: "new-overdraft", Cohort: rule.CohortTreatment},
+				{Rule: "new-overdraft", Cohort: rule.CohortControl},
+			})
+		})
+	})
+}
+
+func TestBuildProfiles(t *testing.T) {
+	Convey("Given a configuration with profiles", t, func() {
+		load := func(source string) config.Config {
+			cfg, err := config.Load(strings.NewReader(source))
+			So(err, ShouldBeNil)
+			return cfg
+		}
+
+		Convey("It should build the rules of each profile", func() {
+			cfg := load(`{"rules": [{"type": "sufficient-limit"}], "profiles": {
+				"basic": [{"type": "sufficient-limit"}],
+				"premium": [{"type": "account-card-active"}, {"type": "overdraft", "params": {"fee": 0}}]
+			}}`)
+			profiles, err := cfg.BuildProfiles()
+			So(err, ShouldBeNil)
+			So(profiles, ShouldHaveLength, 2)
+			So(profiles["basic"], ShouldHaveLength, 1)
+			So(profiles["premium"], ShouldHaveLength, 2)
+		})
+
+		Convey("It should return nil if there are no profiles", func() {
+			profiles, err := load(`{"rules": [{"type": "sufficient-limit"}]}`).BuildProfiles()
+			So(err, ShouldBeNil)
+			So(profiles, ShouldBeNil)
+		})
+
+		Convey("It should return clear errors for invalid profiles", func() {
+			_, err := load(`{"profiles": {"premium": [{"type": "magic"}]}}`).BuildProfiles()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `profile "premium": rule 0: Unknown rule type "magic"`)
+
+			_, err = load(`{"profiles": {"premium": []}}`).BuildProfiles()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `profile "premium": at least one rule`)
+
+			_, err = load(`{"profiles": {"": [{"type": "sufficient-limit"}]}}`).BuildProfiles()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
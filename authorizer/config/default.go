@@ -0,0 +1,21 @@
+package config
+
+import "encoding/json"
+
+// Default returns the configuration of the default rules, validated for every
+// transaction when no configuration file is given. It is defined here rather
+// than in a file so that the application can run without any files.
+func Default() Config {
+	return Config{Rules: []RuleConfig{
+		{Type: "chronological-order"},
+		{Type: "account-card-active"},
+		{Type: "sufficient-limit"},
+		{Type: "merchant-lists"},
+		{Type: "country-lists"},
+		{Type: "allowed-schedule"},
+		{Type: "subscription-amount"},
+		{Type: "limited-frequency", Params: json.RawMessage(`{"max-transactions": 3, "interval": "2m"}`)},
+		{Type: "unique-transactions", Params: json.RawMessage(`{"interval": "2m"}`)},
+		{Type: "category-controls"},
+	}}
+}
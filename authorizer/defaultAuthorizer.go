@@ -1,26 +1,21 @@
 package authorizer
 
 import (
+	"nuledger/authorizer/config"
 	"nuledger/authorizer/rule"
-	"nuledger/authorizer/rules"
-	"time"
-)
-
-const (
-	frequencyAnalysisInterval = 2 * time.Minute
-	maxIntervalTransactions   = 3
 )
 
 // DefaultAuthorizer returns an Authorizer with all the default rules to be
 // validated for every transaction in the system. We could say that this gathers
 // most of the core business logic validations that we want to perform against
 // the transactions in order to authorize them or return their violations.
+//
+// The rules are built from config.Default, so they are the same as the ones of
+// a configuration file with those rules.
 func DefaultAuthorizer() rule.Authorizer {
-	return rule.List{
-		&rules.ChronologicalOrder{},
-		rule.AuthorizerFunc(rules.AccountCardActive),
-		rule.AuthorizerFunc(rules.SufficientLimit),
-		rules.NewLimitedFrequency(maxIntervalTransactions, frequencyAnalysisInterval),
-		rules.NewUniqueTransactions(frequencyAnalysisInterval),
+	list, err := config.Default().Build()
+	if err != nil {
+		panic(err)
 	}
+	return list
 }
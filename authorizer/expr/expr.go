@@ -0,0 +1,253 @@
+// Package expr implements a small expression language for writing custom
+// authorization rules, like `amount > 500 && merchant startsWith "CASINO"`.
+//
+// Expressions are sandboxed: they can only read the declared variables and
+// window aggregates provided by the caller, have no loops nor side effects and
+// are type-checked when compiled, so that evaluating them never fails.
+//
+// The language has number, string and bool values, with the operators:
+//
+//	||  &&  !                       logical operators on bools
+//	==  !=                          equality of values of the same type
+//	<  <=  >  >=                    comparison of numbers
+//	+  -  *  /                      arithmetic on numbers
+//	startsWith  endsWith  contains  case-insensitive string matching
+//	in                              membership in a list, e.g. mcc in ["7995", "7801"]
+//
+// It also has the window aggregates count(window) and sum(window), e.g.
+// count(10m) or sum(24h), whose meaning is defined by the Env of evaluation.
+package expr
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	aggregateCount = "count"
+	aggregateSum   = "sum"
+)
+
+// Type is an enum of the types of values in the expression language.
+type Type int
+
+const (
+	TypeNumber Type = iota + 1
+	TypeString
+	TypeBool
+	typeNumberList
+	typeStringList
+)
+
+var typeNames = map[Type]string{
+	TypeNumber:     "number",
+	TypeString:     "string",
+	TypeBool:       "bool",
+	typeNumberList: "list of numbers",
+	typeStringList: "list of strings",
+}
+
+// String implements the fmt.Stringer interface, returning the type name.
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+func (t Type) isList() bool {
+	return t == typeNumberList || t == typeStringList
+}
+
+func (t Type) list() Type {
+	if t == TypeNumber {
+		return typeNumberList
+	}
+	return typeStringList
+}
+
+func (t Type) elem() Type {
+	if t == typeNumberList {
+		return TypeNumber
+	}
+	return TypeString
+}
+
+// Env is the environment where an expression is evaluated, providing the
+// values of its variables and window aggregates.
+type Env interface {
+	// Var returns the value of a declared variable, which must be a float64,
+	// a string or a bool according to its declared Type.
+	Var(name string) interface{}
+	// Count returns the number of events within the window.
+	Count(window time.Duration) float64
+	// Sum returns the sum of the events values within the window.
+	Sum(window time.Duration) float64
+}
+
+// Expression is a compiled expression, ready to be evaluated.
+type Expression struct {
+	source    string
+	root      node
+	maxWindow time.Duration
+}
+
+// Compile parses and type-checks the source of an expression, which can only
+// refer to the given declared variables and must result in a bool value. It
+// returns an error describing the first problem found in the source.
+func Compile(source string, vars map[string]Type) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, vars: vars}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok.pos, "unexpected %s", describe(tok))
+	}
+	if root.typ() != TypeBool {
+		return nil, errorAt(0, "expression must result in a bool, got %v", root.typ())
+	}
+	return &Expression{source: source, root: root, maxWindow: p.maxWindow}, nil
+}
+
+// Source returns the source the expression was compiled from.
+func (e *Expression) Source() string {
+	return e.source
+}
+
+// MaxWindow returns the largest window used by the aggregates of the
+// expression, or zero if it has no aggregates. It can be used by the caller to
+// know how much history it needs to keep for evaluating them.
+func (e *Expression) MaxWindow() time.Duration {
+	return e.maxWindow
+}
+
+// Eval evaluates the expression in the given environment.
+func (e *Expression) Eval(env Env) bool {
+	return e.root.eval(env).(bool)
+}
+
+// node is a type-checked node of the syntax tree of an expression.
+type node interface {
+	typ() Type
+	eval(env Env) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+	t     Type
+}
+
+func (n *literalNode) typ() Type              { return n.t }
+func (n *literalNode) eval(_ Env) interface{} { return n.value }
+
+type listNode struct {
+	values []interface{}
+	t      Type
+}
+
+func (n *listNode) typ() Type              { return n.t }
+func (n *listNode) eval(_ Env) interface{} { return n.values }
+
+type variableNode struct {
+	name string
+	t    Type
+}
+
+func (n *variableNode) typ() Type                { return n.t }
+func (n *variableNode) eval(env Env) interface{} { return env.Var(n.name) }
+
+type aggregateNode struct {
+	fn     string
+	window time.Duration
+}
+
+func (n *aggregateNode) typ() Type { return TypeNumber }
+func (n *aggregateNode) eval(env Env) interface{} {
+	if n.fn == aggregateCount {
+		return env.Count(n.window)
+	}
+	return env.Sum(n.window)
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) typ() Type { return n.x.typ() }
+func (n *unaryNode) eval(env Env) interface{} {
+	if n.op == "!" {
+		return !n.x.eval(env).(bool)
+	}
+	return -n.x.eval(env).(float64)
+}
+
+type binaryNode struct {
+	op   string
+	x, y node
+	t    Type
+}
+
+func (n *binaryNode) typ() Type { return n.t }
+func (n *binaryNode) eval(env Env) interface{} {
+	switch n.op {
+	case "&&":
+		return n.x.eval(env).(bool) && n.y.eval(env).(bool)
+	case "||":
+		return n.x.eval(env).(bool) || n.y.eval(env).(bool)
+	}
+
+	x, y := n.x.eval(env), n.y.eval(env)
+	switch n.op {
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	case "in":
+		for _, elm := range y.([]interface{}) {
+			if elm == x {
+				return true
+			}
+		}
+		return false
+	case "startsWith":
+		return strings.HasPrefix(strings.ToLower(x.(string)), strings.ToLower(y.(string)))
+	case "endsWith":
+		return strings.HasSuffix(strings.ToLower(x.(string)), strings.ToLower(y.(string)))
+	case "contains":
+		return strings.Contains(strings.ToLower(x.(string)), strings.ToLower(y.(string)))
+	}
+
+	a, b := x.(float64), y.(float64)
+	switch n.op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	default:
+		return a / b
+	}
+}
+
+func sortedNames(vars map[string]Type) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
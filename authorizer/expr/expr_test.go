@@ -0,0 +1,113 @@
+package expr_test
+
+import (
+	"nuledger/authorizer/expr"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testEnv struct {
+	vars   map[string]interface{}
+	events map[time.Duration][]float64
+}
+
+func (e testEnv) Var(name string) interface{} { return e.vars[name] }
+
+func (e testEnv) Count(window time.Duration) float64 { return float64(len(e.events[window])) }
+
+func (e testEnv) Sum(window time.Duration) float64 {
+	sum := 0.0
+	for _, value := range e.events[window] {
+		sum += value
+	}
+	return sum
+}
+
+var testVars = map[string]expr.Type{
+	"amount":   expr.TypeNumber,
+	"merchant": expr.TypeString,
+	"mcc":      expr.TypeString,
+	"foreign":  expr.TypeBool,
+}
+
+func TestExpression(t *testing.T) {
+	Convey("Given an environment", t, func() {
+		env := testEnv{
+			vars: map[string]interface{}{
+				"amount":   600.0,
+				"merchant": "Casino Royale",
+				"mcc":      "7995",
+				"foreign":  false,
+			},
+			events: map[time.Duration][]float64{10 * time.Minute: {10, 20, 30}},
+		}
+		eval := func(source string) bool {
+			expression, err := expr.Compile(source, testVars)
+			So(err, ShouldBeNil)
+			return expression.Eval(env)
+		}
+
+		Convey("It should evaluate comparisons", func() {
+			So(eval(`amount > 500`), ShouldBeTrue)
+			So(eval(`amount <= 500`), ShouldBeFalse)
+			So(eval(`amount == 600 && mcc != "5411"`), ShouldBeTrue)
+			So(eval(`foreign == false`), ShouldBeTrue)
+		})
+		Convey("It should evaluate string operators ignoring case", func() {
+			So(eval(`merchant startsWith "CASINO"`), ShouldBeTrue)
+			So(eval(`merchant endsWith "royale"`), ShouldBeTrue)
+			So(eval(`merchant contains "o r"`), ShouldBeTrue)
+			So(eval(`merchant startsWith "Royale"`), ShouldBeFalse)
+		})
+		Convey("It should evaluate list membership", func() {
+			So(eval(`mcc in ["7995", "7801"]`), ShouldBeTrue)
+			So(eval(`amount in [1, 2.5]`), ShouldBeFalse)
+		})
+		Convey("It should respect operator precedence", func() {
+			So(eval(`amount - 100 * 2 == 400`), ShouldBeTrue)
+			So(eval(`(amount - 100) * 2 == 1000`), ShouldBeTrue)
+			So(eval(`foreign && false || true`), ShouldBeTrue)
+			So(eval(`!foreign && -amount < 0`), ShouldBeTrue)
+		})
+		Convey("It should evaluate window aggregates", func() {
+			expression, err := expr.Compile(`count(10m) >= 3 && sum(10m) + amount > 600 || count(1h) > 0`, testVars)
+			So(err, ShouldBeNil)
+			So(expression.Eval(env), ShouldBeTrue)
+			So(expression.MaxWindow(), ShouldEqual, time.Hour)
+		})
+	})
+
+	Convey("Given invalid expressions", t, func() {
+		testError := func(source, expected string) {
+			expression, err := expr.Compile(source, testVars)
+			So(expression, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, expected)
+		}
+
+		Convey("It should return syntax errors with their positions", func() {
+			testError(`amount > `, "at position 9: unexpected end of expression")
+			testError(`amount > 500 500`, `at position 13: unexpected "500"`)
+			testError(`(amount > 500`, `expected ")"`)
+			testError(`merchant == "Casino`, "unterminated string")
+			testError(`amount > 5 # 2`, "unexpected character '#'")
+			testError(`count(10x) > 1`, `invalid duration "10x"`)
+		})
+		Convey("It should return errors for unknown variables", func() {
+			testError(`balance > 500`, `unknown variable "balance", must be one of: amount, foreign, mcc, merchant`)
+		})
+		Convey("It should return type errors", func() {
+			testError(`amount`, "must result in a bool")
+			testError(`merchant > 500`, "operator > is not defined for string and number")
+			testError(`amount startsWith "5"`, "operator startsWith is not defined for number and string")
+			testError(`amount && foreign`, "operator && requires bool operands")
+			testError(`merchant + "x" == "y"`, "operator + requires number operands")
+			testError(`!amount`, "operator ! requires a bool operand")
+			testError(`mcc in ["a", 1]`, "single type")
+			testError(`mcc in "a"`, "operator in is not defined")
+			testError(`count(amount) > 1`, "count requires a positive duration")
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tokenKind is an enum of the kinds of tokens of the expression language.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+// token is a single lexical unit of an expression, with its position in the
+// source for error messages.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+
+	number   float64
+	duration time.Duration
+	str      string
+}
+
+// operators are all the punctuation operators, with the longer ones first so
+// that they take precedence when lexing.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ","}
+
+// tokenize splits the source of an expression in its tokens, always ending
+// with a tokenEOF.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(source); {
+		c := rune(source[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case isDigit(c):
+			tok, err := lexNumber(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += len(tok.text)
+		case c == '"':
+			tok, err := lexString(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += len(tok.text)
+		case isIdentStart(c):
+			end := pos + 1
+			for end < len(source) && isIdentPart(rune(source[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+		default:
+			op := matchOperator(source[pos:])
+			if op == "" {
+				return nil, errorAt(pos, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// lexNumber reads a number or a duration, which is a number immediately
+// followed by units (e.g. "10m" or "1h30m").
+func lexNumber(source string, pos int) (token, error) {
+	end := pos
+	hasUnits := false
+	for end < len(source) && (isDigit(rune(source[end])) || source[end] == '.' || unicode.IsLetter(rune(source[end]))) {
+		hasUnits = hasUnits || unicode.IsLetter(rune(source[end]))
+		end++
+	}
+	text := source[pos:end]
+
+	if hasUnits {
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return token{}, errorAt(pos, "invalid duration %q", text)
+		}
+		return token{kind: tokenDuration, text: text, pos: pos, duration: duration}, nil
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, errorAt(pos, "invalid number %q", text)
+	}
+	return token{kind: tokenNumber, text: text, pos: pos, number: number}, nil
+}
+
+// lexString reads a double-quoted string literal, which supports the same
+// escape sequences as Go strings.
+func lexString(source string, pos int) (token, error) {
+	for end := pos + 1; end < len(source); end++ {
+		switch source[end] {
+		case '\\':
+			end++
+		case '"':
+			text := source[pos : end+1]
+			str, err := strconv.Unquote(text)
+			if err != nil {
+				return token{}, errorAt(pos, "invalid string %s", text)
+			}
+			return token{kind: tokenString, text: text, pos: pos, str: str}, nil
+		}
+	}
+	return token{}, errorAt(pos, "unterminated string")
+}
+
+func matchOperator(source string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(source, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.'
+}
+
+// errorAt returns an error for a problem at the given position of the source.
+func errorAt(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", pos, fmt.Sprintf(format, args...))
+}
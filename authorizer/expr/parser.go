@@ -0,0 +1,261 @@
+package expr
+
+import (
+	"strings"
+	"time"
+)
+
+// parser is a recursive descent parser of the expression language, which also
+// type-checks the expression as it builds its syntax tree.
+//
+// The grammar, from the lowest to the highest precedence, is:
+//
+//	or         = and { "||" and }
+//	and        = comparison { "&&" comparison }
+//	comparison = sum [ comparator sum ]
+//	sum        = product { ("+" | "-") product }
+//	product    = unary { ("*" | "/") unary }
+//	unary      = ("!" | "-") unary | primary
+//	primary    = number | string | "true" | "false" | list | aggregate
+//	           | variable | "(" or ")"
+//	list       = "[" literal { "," literal } "]"
+//	aggregate  = ("count" | "sum") "(" duration ")"
+type parser struct {
+	tokens    []token
+	pos       int
+	vars      map[string]Type
+	maxWindow time.Duration
+}
+
+// comparators are the operators of the comparison rule of the grammar.
+var comparators = []string{"==", "!=", "<", "<=", ">", ">=", "startsWith", "endsWith", "contains", "in"}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or
+// keywords, returning whether it did so.
+func (p *parser) accept(texts ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return tok, false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) expect(text string) error {
+	if tok, ok := p.accept(text); !ok {
+		return errorAt(tok.pos, "expected %q but found %s", text, describe(tok))
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseComparison)
+}
+
+func (p *parser) parseLogical(op string, parseOperand func() (node, error)) (node, error) {
+	x, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(op)
+		if !ok {
+			return x, nil
+		}
+		y, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != TypeBool || y.typ() != TypeBool {
+			return nil, errorAt(tok.pos, "operator %s requires bool operands, got %v and %v", op, x.typ(), y.typ())
+		}
+		x = &binaryNode{op: op, x: x, y: y, t: TypeBool}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	x, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.accept(comparators...)
+	if !ok {
+		return x, nil
+	}
+	y, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	op, xt, yt := tok.text, x.typ(), y.typ()
+	switch {
+	case op == "==" || op == "!=":
+		ok = xt == yt && !xt.isList()
+	case op == "<" || op == "<=" || op == ">" || op == ">=":
+		ok = xt == TypeNumber && yt == TypeNumber
+	case op == "in":
+		ok = yt.isList() && yt.elem() == xt
+	default:
+		ok = xt == TypeString && yt == TypeString
+	}
+	if !ok {
+		return nil, errorAt(tok.pos, "operator %s is not defined for %v and %v", op, xt, yt)
+	}
+	return &binaryNode{op: op, x: x, y: y, t: TypeBool}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	return p.parseArithmetic([]string{"+", "-"}, p.parseProduct)
+}
+
+func (p *parser) parseProduct() (node, error) {
+	return p.parseArithmetic([]string{"*", "/"}, p.parseUnary)
+}
+
+func (p *parser) parseArithmetic(ops []string, parseOperand func() (node, error)) (node, error) {
+	x, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != TypeNumber || y.typ() != TypeNumber {
+			return nil, errorAt(tok.pos, "operator %s requires number operands, got %v and %v", tok.text, x.typ(), y.typ())
+		}
+		x = &binaryNode{op: tok.text, x: x, y: y, t: TypeNumber}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok, ok := p.accept("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	expected := TypeNumber
+	if tok.text == "!" {
+		expected = TypeBool
+	}
+	if x.typ() != expected {
+		return nil, errorAt(tok.pos, "operator %s requires a %v operand, got %v", tok.text, expected, x.typ())
+	}
+	return &unaryNode{op: tok.text, x: x}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{value: tok.number, t: TypeNumber}, nil
+	case tokenString:
+		return &literalNode{value: tok.str, t: TypeString}, nil
+	case tokenIdent:
+		return p.parseIdent(tok)
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			return p.parseList(tok)
+		}
+	}
+	return nil, errorAt(tok.pos, "unexpected %s", describe(tok))
+}
+
+func (p *parser) parseIdent(tok token) (node, error) {
+	switch tok.text {
+	case "true", "false":
+		return &literalNode{value: tok.text == "true", t: TypeBool}, nil
+	case aggregateCount, aggregateSum:
+		return p.parseAggregate(tok)
+	}
+
+	t, ok := p.vars[tok.text]
+	if !ok {
+		return nil, errorAt(tok.pos, "unknown variable %q, must be one of: %s", tok.text, strings.Join(sortedNames(p.vars), ", "))
+	}
+	return &variableNode{name: tok.text, t: t}, nil
+}
+
+func (p *parser) parseAggregate(fn token) (node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	tok := p.next()
+	if tok.kind != tokenDuration || tok.duration <= 0 {
+		return nil, errorAt(tok.pos, "%s requires a positive duration (e.g. 10m) but found %s", fn.text, describe(tok))
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if tok.duration > p.maxWindow {
+		p.maxWindow = tok.duration
+	}
+	return &aggregateNode{fn: fn.text, window: tok.duration}, nil
+}
+
+func (p *parser) parseList(open token) (node, error) {
+	list := &listNode{}
+	for {
+		x, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		literal, ok := x.(*literalNode)
+		if !ok || literal.t == TypeBool {
+			return nil, errorAt(open.pos, "lists can only have number or string literals")
+		}
+		if len(list.values) > 0 && literal.t != list.t.elem() {
+			return nil, errorAt(open.pos, "lists must have elements of a single type")
+		}
+		list.values = append(list.values, literal.value)
+		list.t = literal.t.list()
+
+		if _, ok := p.accept(","); !ok {
+			return list, p.expect("]")
+		}
+	}
+}
+
+func describe(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of expression"
+	}
+	return "\"" + tok.text + "\""
+}
@@ -3,6 +3,7 @@ package authorizer
 import (
 	"errors"
 	"fmt"
+	"nuledger/authorizer/rule"
 	"nuledger/iop"
 	"nuledger/model"
 	"nuledger/model/violation"
@@ -13,14 +14,21 @@ import (
 // input/output processing pipeline and the actual ledger system which manages
 // the account state and performs transactions. It basically interprets the JSON
 // objects received and calls the correct higher-level APIs from the Ledger.
+//
+// In Verbose mode, the output of transactions also includes the decision of
+// each rule that authorized them. In StructuredViolations mode, the violations
+// are written as objects with their messages and metadata instead of only
+// their codes.
 type Handler struct {
 	Ledger
+	Verbose              bool
+	StructuredViolations bool
 }
 
 // NewHandler creates a new Handler with a Ledger with all the default
 // authorizers from DefaultAuthorizer.
 func NewHandler() iop.DataHandler {
-	return &Handler{NewLedger(DefaultAuthorizer())}
+	return &Handler{Ledger: NewLedger(DefaultAuthorizer())}
 }
 
 // Handle implements the iop.DataHandler interface, receiving JSON objects
@@ -33,19 +41,50 @@ func (h *Handler) Handle(op iop.OperationInput) (iop.StateOutput, error) {
 		return iop.StateOutput{}, fmt.Errorf("Bad operation object: %w", err)
 	}
 
-	var account *model.Account
+	var (
+		account *model.Account
+		trace   []model.RuleDecision
+		risk    *model.RiskAssessment
+	)
 	switch opType {
 	case operationTypeCreateAccount:
 		account, err = h.CreateAccount(*op.Account)
 	case operationTypePerformTransaction:
-		account, err = h.PerformTransaction(*op.Transaction)
+		if h.Verbose {
+			account, trace, err = h.TraceTransaction(*op.Transaction)
+		} else {
+			account, err = h.PerformTransaction(*op.Transaction)
+		}
+		risk = h.RiskAssessment(op.Transaction.AccountID)
+	case operationTypeUpdateCategoryControls:
+		account, err = h.UpdateCategoryControls(*op.CategoryControls)
+	case operationTypeUpdateMerchantAllowlist:
+		account, err = h.UpdateMerchantAllowlist(*op.MerchantAllowlist)
+	case operationTypeUpdateMerchantBlocklist:
+		account, err = h.UpdateMerchantBlocklist(*op.MerchantBlocklist)
+	case operationTypeUpdateSubscriptions:
+		account, err = h.UpdateSubscriptions(*op.Subscriptions)
+	case operationTypeUpdateProfile:
+		account, err = h.UpdateProfile(*op.Profile)
 	}
 
+	err, shadowErr := rule.SplitShadow(err)
 	violations, err := extractViolations(err)
 	if err != nil {
 		return iop.StateOutput{}, err
 	}
-	return iop.StateOutput{account, violations}, nil
+	output := iop.StateOutput{Account: account, Violations: violationCodes(violations), Risk: risk, Trace: trace}
+	if h.StructuredViolations {
+		output.ViolationDetails = violationOutputs(violations, account)
+	}
+	if shadowErr != nil {
+		shadowViolations, err := extractViolations(shadowErr)
+		if err != nil {
+			return iop.StateOutput{}, err
+		}
+		output.ShadowViolations = violationCodes(shadowViolations)
+	}
+	return output, nil
 }
 
 // operationType is a helper enum to identify the kind of operation to be
@@ -56,6 +95,11 @@ const (
 	operationTypeUnknown operationType = iota
 	operationTypeCreateAccount
 	operationTypePerformTransaction
+	operationTypeUpdateCategoryControls
+	operationTypeUpdateMerchantAllowlist
+	operationTypeUpdateMerchantBlocklist
+	operationTypeUpdateSubscriptions
+	operationTypeUpdateProfile
 )
 
 // getOperationType receives the input JSON object and returns what is the
@@ -63,40 +107,99 @@ const (
 // in case of any semantic issues with the object (e.g. specifying multiple
 // operations or none of them).
 func getOperationType(op iop.OperationInput) (operationType, error) {
-	hasAccount := op.Account != nil
-	hasTransaction := op.Transaction != nil
-	if hasAccount == hasTransaction {
-		return operationTypeUnknown, errors.New(`Must have exactly 1 of "account" or "transaction" fields set`)
+	var opTypes []operationType
+	if op.Account != nil {
+		opTypes = append(opTypes, operationTypeCreateAccount)
 	}
-	if hasAccount {
-		return operationTypeCreateAccount, nil
+	if op.Transaction != nil {
+		opTypes = append(opTypes, operationTypePerformTransaction)
+	}
+	if op.CategoryControls != nil {
+		opTypes = append(opTypes, operationTypeUpdateCategoryControls)
+	}
+	if op.MerchantAllowlist != nil {
+		opTypes = append(opTypes, operationTypeUpdateMerchantAllowlist)
+	}
+	if op.MerchantBlocklist != nil {
+		opTypes = append(opTypes, operationTypeUpdateMerchantBlocklist)
+	}
+	if op.Subscriptions != nil {
+		opTypes = append(opTypes, operationTypeUpdateSubscriptions)
+	}
+	if op.Profile != nil {
+		opTypes = append(opTypes, operationTypeUpdateProfile)
+	}
+
+	if len(opTypes) != 1 {
+		return operationTypeUnknown, errors.New(`Must have exactly 1 operation field set (e.g. "account" or "transaction")`)
 	}
-	return operationTypePerformTransaction, nil
+	return opTypes[0], nil
 }
 
 // extractViolations receives an error and tries to fetch the specific violation
-// codes that might be represented by it. If there are any errors that are not
-// violation codes they are returned in the second return value.
-func extractViolations(err error) ([]violation.Code, error) {
+// errors that might be represented by it. If there are any errors that are not
+// violations, or violations with codes missing from the violation registry,
+// they are returned in the second return value.
+func extractViolations(err error) ([]violation.Error, error) {
 	var verr violation.Error
 	if errors.As(err, &verr) {
-		return []violation.Code{verr.Code}, nil
+		if err := checkRegistered(verr); err != nil {
+			return []violation.Error{}, err
+		}
+		return []violation.Error{verr}, nil
 	}
 	var aggErr util.AggregateError
 	if !errors.As(err, &aggErr) {
-		return []violation.Code{}, err
+		return []violation.Error{}, err
 	}
 
 	var (
-		violations = make([]violation.Code, 0, len(aggErr.Errors))
+		violations = make([]violation.Error, 0, len(aggErr.Errors))
 		fatalErrs  []error
 	)
 	for _, innerErr := range aggErr.Errors {
-		if errors.As(innerErr, &verr) {
-			violations = append(violations, verr.Code)
-		} else {
+		if !errors.As(innerErr, &verr) {
 			fatalErrs = append(fatalErrs, innerErr)
+		} else if err := checkRegistered(verr); err != nil {
+			fatalErrs = append(fatalErrs, err)
+		} else {
+			violations = append(violations, verr)
 		}
 	}
 	return violations, util.AggregateErrors(fatalErrs)
 }
+
+// checkRegistered returns an error if the code of the violation is not in the
+// violation registry, which means that some rule is not declaring its codes.
+func checkRegistered(verr violation.Error) error {
+	if _, ok := violation.Lookup(verr.Code); !ok {
+		return fmt.Errorf("Unregistered violation code %q: %w", verr.Code, verr)
+	}
+	return nil
+}
+
+// violationCodes returns the codes of the given violations, for the plain
+// output of violations.
+func violationCodes(violations []violation.Error) []violation.Code {
+	codes := make([]violation.Code, len(violations))
+	for i, verr := range violations {
+		codes[i] = verr.Code
+	}
+	return codes
+}
+
+// violationOutputs returns the given violations as objects for the structured
+// output of violations, with their messages localized to the locale of the
+// account, or in English if there is no account or it has no locale.
+func violationOutputs(violations []violation.Error, account *model.Account) []iop.ViolationOutput {
+	var locale violation.Locale
+	if account != nil {
+		locale = account.Locale
+	}
+	outputs := make([]iop.ViolationOutput, len(violations))
+	for i, verr := range violations {
+		message := violation.Localize(verr, locale)
+		outputs[i] = iop.ViolationOutput{Code: verr.Code, Message: message, Metadata: verr.Details}
+	}
+	return outputs
+}
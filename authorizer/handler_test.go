@@ -20,6 +20,14 @@ import (
 
 var startTime = time.Date(2021, time.March, 31, 21, 11, 43, 0, time.Local)
 
+func init() {
+	violation.MustRegister(
+		violation.Definition{Code: "custom-validation-code", Severity: violation.SeverityLow},
+		violation.Definition{Code: "yet-another-validation-code", Severity: violation.SeverityLow},
+		violation.Definition{Code: "shadow-validation-code", Severity: violation.SeverityLow},
+	)
+}
+
 func TestHandler(t *testing.T) {
 	Convey("Given an authorizer Handler", t, func() {
 		ctrl := gomock.NewController(t)
@@ -65,6 +73,38 @@ func TestHandler(t *testing.T) {
 					testHandlerOperations(ctrl, validate, nil, returnedError)
 				})
 
+				Convey("It should return shadow violations separately", func() {
+					returnedError := util.AggregateError{Errors: []error{
+						violation.NewError("custom-validation-code", "Hello violations"),
+						rule.ShadowError{Err: violation.NewError("shadow-validation-code", "Hello shadows")},
+					}}
+					expected := iop.StateOutput{
+						Account:          nil,
+						Violations:       []violation.Code{"custom-validation-code"},
+						ShadowViolations: []violation.Code{"shadow-validation-code"},
+					}
+
+					validate := func(output iop.StateOutput, err error) {
+						So(err, ShouldBeNil)
+						So(output, ShouldResemble, expected)
+					}
+					testHandlerOperations(ctrl, validate, nil, returnedError)
+				})
+
+				Convey("Violations with unregistered codes should be fatal", func() {
+					returnedError := util.AggregateError{Errors: []error{
+						violation.NewError("custom-validation-code", "Hello violations"),
+						violation.NewError("unregistered-validation-code", "Hello strangers"),
+					}}
+
+					validate := func(output iop.StateOutput, err error) {
+						So(err, ShouldNotBeNil)
+						So(output, ShouldBeZeroValue)
+						So(err.Error(), ShouldContainSubstring, "unregistered-validation-code")
+					}
+					testHandlerOperations(ctrl, validate, nil, returnedError)
+				})
+
 				Convey("Any other error should be propagated", func() {
 					regularErr := errors.New("This is just a regular error")
 
@@ -94,13 +134,148 @@ func TestHandler(t *testing.T) {
 	})
 }
 
+func TestRiskHandler(t *testing.T) {
+	Convey("Given an authorizer Handler with a risk assessment of the transaction", t, func() {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ledger := mock_authorizer.NewMockLedger(ctrl)
+		handler := &authorizer.Handler{Ledger: ledger}
+		transaction := &model.Transaction{AccountID: "the-account", Merchant: "Amazon Web Services", Amount: 142, Time: startTime}
+		returnedAccount := &model.Account{ActiveCard: true, AvailableLimit: 100}
+		risk := &model.RiskAssessment{Score: 72.5, Signals: []model.RiskSignal{model.RiskSignalNightTime}}
+		ledger.EXPECT().RiskAssessment(gomock.Eq("the-account")).Return(risk)
+
+		Convey("It should include it in the output of declined transactions", func() {
+			ledger.EXPECT().
+				PerformTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, violation.NewError(violation.HighRisk, "Too risky").WithDetails(risk))
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output, ShouldResemble, iop.StateOutput{Account: returnedAccount, Violations: []violation.Code{violation.HighRisk}, Risk: risk})
+		})
+
+		Convey("It should include it in the output of approved transactions", func() {
+			ledger.EXPECT().
+				PerformTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, nil)
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output, ShouldResemble, iop.StateOutput{Account: returnedAccount, Violations: []violation.Code{}, Risk: risk})
+		})
+	})
+}
+
+func TestVerboseHandler(t *testing.T) {
+	Convey("Given an authorizer Handler in verbose mode", t, func() {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ledger := mock_authorizer.NewMockLedger(ctrl)
+		ledger.EXPECT().RiskAssessment(gomock.Any()).AnyTimes()
+		handler := &authorizer.Handler{Ledger: ledger, Verbose: true}
+
+		Convey("It should include the trace of transactions in the output", func() {
+			transaction := &model.Transaction{Merchant: "Amazon Web Services", Amount: 142, Time: startTime}
+			returnedAccount := &model.Account{ActiveCard: true, AvailableLimit: 100}
+			trace := []model.RuleDecision{
+				{Rule: "rules.AccountCardActive", Outcome: model.RuleOutcomePass},
+				{Rule: "rules.SufficientLimit", Outcome: model.RuleOutcomeFail, Violations: []violation.Code{violation.InsufficientLimit}},
+			}
+			ledger.EXPECT().
+				TraceTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, trace, violation.ErrorInsufficientLimit)
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output, ShouldResemble, iop.StateOutput{
+				Account:    returnedAccount,
+				Violations: []violation.Code{violation.InsufficientLimit},
+				Trace:      trace,
+			})
+		})
+	})
+}
+
+func TestStructuredViolationsHandler(t *testing.T) {
+	Convey("Given an authorizer Handler in structured violations mode", t, func() {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ledger := mock_authorizer.NewMockLedger(ctrl)
+		ledger.EXPECT().RiskAssessment(gomock.Any()).AnyTimes()
+		handler := &authorizer.Handler{Ledger: ledger, StructuredViolations: true}
+		transaction := &model.Transaction{Merchant: "Amazon Web Services", Amount: 142, Time: startTime}
+		returnedAccount := &model.Account{ActiveCard: true, AvailableLimit: 100}
+
+		Convey("It should include the messages and details of the violations", func() {
+			details := &rules.LimitDetails{Remaining: 100}
+			ledger.EXPECT().
+				PerformTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, util.AggregateError{Errors: []error{
+					violation.ErrorInsufficientLimit.WithDetails(details),
+					violation.NewError("custom-validation-code", "Hello violations"),
+				}})
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output, ShouldResemble, iop.StateOutput{
+				Account:    returnedAccount,
+				Violations: []violation.Code{violation.InsufficientLimit, "custom-validation-code"},
+				ViolationDetails: []iop.ViolationOutput{
+					{Code: violation.InsufficientLimit, Message: "Transaction amount is higher than available limit of 100", Metadata: details},
+					{Code: "custom-validation-code", Message: "Hello violations"},
+				},
+			})
+		})
+
+		Convey("It should localize the messages to the locale of the account", func() {
+			returnedAccount.Locale = violation.LocalePortuguese
+			ledger.EXPECT().
+				PerformTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, util.AggregateError{Errors: []error{
+					violation.ErrorInsufficientLimit.WithDetails(&rules.LimitDetails{Remaining: 100}),
+					violation.NewError("custom-validation-code", "Hello violations"),
+				}})
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output.ViolationDetails, ShouldHaveLength, 2)
+			So(output.ViolationDetails[0].Message, ShouldEqual, "O valor da transação é maior que o limite disponível de 100")
+			So(output.ViolationDetails[1].Message, ShouldEqual, "Hello violations")
+		})
+
+		Convey("It should have English messages consistent with the registered violations", func() {
+			details := map[string]interface{}{"remaining": 100, "score": 0.9}
+			for _, def := range violation.Registered() {
+				verr := violation.NewError(def.Code, def.Description).WithDetails(details)
+				So(violation.Localize(verr, ""), ShouldStartWith, def.Description)
+			}
+		})
+
+		Convey("It should have no violation details on success", func() {
+			ledger.EXPECT().
+				PerformTransaction(gomock.Eq(*transaction)).
+				Return(returnedAccount, nil)
+
+			output, err := handler.Handle(iop.OperationInput{Transaction: transaction})
+			So(err, ShouldBeNil)
+			So(output.ViolationDetails, ShouldNotBeNil)
+			So(output.ViolationDetails, ShouldBeEmpty)
+		})
+	})
+}
+
 func TestHandlerBadInput(t *testing.T) {
 	Convey("Given the authorizer Handler gets some bad input", t, func() {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		ledger := mock_authorizer.NewMockLedger(ctrl)
-		var handler iop.DataHandler = &authorizer.Handler{ledger}
+		ledger.EXPECT().RiskAssessment(gomock.Any()).AnyTimes()
+		var handler iop.DataHandler = &authorizer.Handler{Ledger: ledger}
 
 		Convey("It should return a fatal error", func() {
 			test := func(input iop.OperationInput) {
@@ -113,7 +288,8 @@ func TestHandlerBadInput(t *testing.T) {
 				test(iop.OperationInput{})
 			})
 			Convey("For ambiguous operations", func() {
-				test(iop.OperationInput{&model.Account{}, &model.Transaction{}})
+				test(iop.OperationInput{Account: &model.Account{}, Transaction: &model.Transaction{}})
+				test(iop.OperationInput{Transaction: &model.Transaction{}, CategoryControls: &model.CategoryControlsUpdate{}})
 			})
 		})
 
@@ -160,14 +336,20 @@ func TestDefaultAuthorizers(t *testing.T) {
 		Convey("They should be a rule list", func() {
 			So(authzer, ShouldHaveSameTypeAs, rule.List{})
 
-			list := authzer.(rule.List)
+			list := unnamed(authzer.(rule.List))
 
 			Convey("With all required authorization rules", func() {
-				So(list, ShouldHaveLength, 5)
+				So(list, ShouldHaveLength, 10)
 				So(list, ShouldContain, &rules.ChronologicalOrder{})
-				So(freqAnalyzerCount(list), ShouldEqual, 2)
+				So(list, ShouldContain, rules.NewCategoryControls())
+				So(freqAnalyzerCount(list), ShouldEqual, 1)
+				So(list, ShouldContain, rules.NewUniqueTransactions(rules.UniqueTransactionsConfig{Interval: 2 * time.Minute, CategoryIntervals: map[model.Category]time.Duration{}}))
 				So(containsAuthFunc(list, rules.AccountCardActive), ShouldBeTrue)
 				So(containsAuthFunc(list, rules.SufficientLimit), ShouldBeTrue)
+				So(containsAuthFunc(list, rules.MerchantLists), ShouldBeTrue)
+				So(containsAuthFunc(list, rules.CountryLists), ShouldBeTrue)
+				So(containsAuthFunc(list, rules.AllowedSchedule), ShouldBeTrue)
+				So(containsAuthFunc(list, rules.SubscriptionAmount), ShouldBeTrue)
 			})
 		})
 	})
@@ -186,6 +368,19 @@ func TestDefaultAuthorizers(t *testing.T) {
 	})
 }
 
+// unnamed returns the authorizers of the list without their rule.Named
+// wrappers, if any.
+func unnamed(list rule.List) rule.List {
+	authzers := make(rule.List, len(list))
+	for i, auth := range list {
+		if named, isNamed := auth.(rule.Named); isNamed {
+			auth = named.Authorizer
+		}
+		authzers[i] = auth
+	}
+	return authzers
+}
+
 func freqAnalyzerCount(list rule.List) int {
 	count := 0
 	for _, auth := range list {
@@ -209,7 +404,8 @@ func containsAuthFunc(list rule.List, authFunc rule.AuthorizerFunc) bool {
 
 func testHandlerOperations(ctrl *gomock.Controller, validate func(iop.StateOutput, error), returnAccount *model.Account, returnErr error) {
 	ledger := mock_authorizer.NewMockLedger(ctrl)
-	var handler iop.DataHandler = &authorizer.Handler{ledger}
+	ledger.EXPECT().RiskAssessment(gomock.Any()).AnyTimes()
+	var handler iop.DataHandler = &authorizer.Handler{Ledger: ledger}
 
 	Convey("For CreateAccount (Account) operation", func() {
 		account := &model.Account{ActiveCard: true, AvailableLimit: 20210902}
@@ -231,4 +427,57 @@ func testHandlerOperations(ctrl *gomock.Controller, validate func(iop.StateOutpu
 
 		validate(handler.Handle(performTxOp))
 	})
+	Convey("For UpdateCategoryControls (CategoryControls) operation", func() {
+		update := &model.CategoryControlsUpdate{
+			AccountID: "the-account",
+			Controls:  map[model.Category]model.CategoryControl{model.CategoryGambling: {Blocked: true}},
+		}
+		updateControlsOp := iop.OperationInput{CategoryControls: update}
+
+		ledger.EXPECT().
+			UpdateCategoryControls(gomock.Eq(*update)).
+			Return(returnAccount, returnErr)
+
+		validate(handler.Handle(updateControlsOp))
+	})
+	Convey("For UpdateMerchantAllowlist (MerchantAllowlist) operation", func() {
+		update := &model.MerchantListUpdate{AccountID: "the-account", Merchants: []string{"Uber*"}}
+		updateAllowlistOp := iop.OperationInput{MerchantAllowlist: update}
+
+		ledger.EXPECT().
+			UpdateMerchantAllowlist(gomock.Eq(*update)).
+			Return(returnAccount, returnErr)
+
+		validate(handler.Handle(updateAllowlistOp))
+	})
+	Convey("For UpdateMerchantBlocklist (MerchantBlocklist) operation", func() {
+		update := &model.MerchantListUpdate{AccountID: "the-account", Merchants: []string{"Casino*"}}
+		updateBlocklistOp := iop.OperationInput{MerchantBlocklist: update}
+
+		ledger.EXPECT().
+			UpdateMerchantBlocklist(gomock.Eq(*update)).
+			Return(returnAccount, returnErr)
+
+		validate(handler.Handle(updateBlocklistOp))
+	})
+	Convey("For UpdateSubscriptions (Subscriptions) operation", func() {
+		update := &model.SubscriptionsUpdate{AccountID: "the-account", Subscriptions: []model.Subscription{{ID: "sub", MaxAmount: 30}}}
+		updateSubscriptionsOp := iop.OperationInput{Subscriptions: update}
+
+		ledger.EXPECT().
+			UpdateSubscriptions(gomock.Eq(*update)).
+			Return(returnAccount, returnErr)
+
+		validate(handler.Handle(updateSubscriptionsOp))
+	})
+	Convey("For UpdateProfile (Profile) operation", func() {
+		update := &model.ProfileUpdate{AccountID: "the-account", Profile: "premium"}
+		updateProfileOp := iop.OperationInput{Profile: update}
+
+		ledger.EXPECT().
+			UpdateProfile(gomock.Eq(*update)).
+			Return(returnAccount, returnErr)
+
+		validate(handler.Handle(updateProfileOp))
+	})
 }
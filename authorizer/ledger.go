@@ -1,13 +1,41 @@
 package authorizer
 
 import (
+	"fmt"
 	"nuledger/authorizer/rule"
 	"nuledger/model"
 	"nuledger/model/violation"
+	"time"
 )
 
 //go:generate ../gen_mocks.sh ledger.go
 
+// init registers the violation codes returned by the ledger itself.
+func init() {
+	violation.MustRegister(
+		violation.Definition{
+			Code:        violation.AccountNotInitialized,
+			Severity:    violation.SeverityHigh,
+			Description: violation.ErrorAccountNotInitialized.Message,
+		},
+		violation.Definition{
+			Code:        violation.AccountAlreadyInitialized,
+			Severity:    violation.SeverityLow,
+			Description: violation.ErrorAccountAlreadyInitialized.Message,
+		},
+		violation.Definition{
+			Code:        violation.UnknownProfile,
+			Severity:    violation.SeverityHigh,
+			Description: violation.ErrorUnknownProfile.Message,
+		},
+		violation.Definition{
+			Code:        violation.InvalidAccountConfig,
+			Severity:    violation.SeverityHigh,
+			Description: violation.ErrorInvalidAccountConfig.Message,
+		},
+	)
+}
+
 // Ledger is the main component responsible for managing the account and
 // performing transactions on it.
 type Ledger interface {
@@ -27,8 +55,37 @@ type Ledger interface {
 	// performed, in which case the returned account state must be the same
 	// unmodified state of the account as before the attempt, with nil
 	// representing a non-exsiting account. If the transaction is performed
-	// successfully, the returned account will have the updated state (balance).
+	// successfully, the returned account will have the updated state (balance)
+	// and the error can only have rule.ShadowErrors to be reported.
 	PerformTransaction(transaction model.Transaction) (*model.Account, error)
+	// RiskAssessment returns the risk assessment of the last transaction
+	// attempted in the account, whether performed or not, or nil if none of the
+	// rules assessed it (see rule.Assessor).
+	RiskAssessment(accountID string) *model.RiskAssessment
+	// TraceTransaction performs a transaction exactly like PerformTransaction,
+	// but also returns the decision of each rule that authorized it, for
+	// explaining the outcome. There are no decisions if there is no account.
+	TraceTransaction(transaction model.Transaction) (*model.Account, []model.RuleDecision, error)
+	// UpdateCategoryControls replaces the merchant category controls of an
+	// existing account. It returns the final state of the account, or nil and
+	// an error if the account doesn't exist.
+	UpdateCategoryControls(update model.CategoryControlsUpdate) (*model.Account, error)
+	// UpdateMerchantAllowlist replaces the merchant allowlist of an existing
+	// account. It returns the final state of the account, or nil and an error
+	// if the account doesn't exist.
+	UpdateMerchantAllowlist(update model.MerchantListUpdate) (*model.Account, error)
+	// UpdateMerchantBlocklist replaces the merchant blocklist of an existing
+	// account, analogously to UpdateMerchantAllowlist.
+	UpdateMerchantBlocklist(update model.MerchantListUpdate) (*model.Account, error)
+	// UpdateSubscriptions replaces the registered subscriptions of an existing
+	// account. It returns the final state of the account, or nil and an error
+	// if the account doesn't exist.
+	UpdateSubscriptions(update model.SubscriptionsUpdate) (*model.Account, error)
+	// UpdateProfile switches the profile of an existing account, changing the
+	// rules used to authorize its further transactions. It returns the final
+	// state of the account, or an error if the account doesn't exist or the
+	// profile is unknown.
+	UpdateProfile(update model.ProfileUpdate) (*model.Account, error)
 }
 
 // NewLedger creates an AuthLedger object with the provided Authorizer, which is
@@ -40,47 +97,203 @@ type Ledger interface {
 // objects by value (otherwise they'd all have to repeat the same not-nil
 // validation themselves).
 func NewLedger(authorizer rule.Authorizer) *AuthLedger {
-	return &AuthLedger{accounts: map[string]*model.Account{}, authzer: authorizer}
+	return NewProfileLedger(authorizer, nil)
+}
+
+// NewProfileLedger creates an AuthLedger analogous to NewLedger, but also with
+// a registry of profiles, each one with the Authorizer used for the accounts
+// with that profile. The default authorizer is used for the accounts without a
+// profile.
+//
+// The state of stateful rules (e.g. frequency limits) is kept by each profile
+// authorizer, which only sees the transactions authorized while the account has
+// its profile. So switching profiles neither carries that state over to the new
+// profile nor resets it, which is still there if the account switches back.
+// The chronological order of the transactions of each account is enforced by
+// the ledger itself though, so that it holds across all of its profiles.
+func NewProfileLedger(authorizer rule.Authorizer, profiles map[string]rule.Authorizer) *AuthLedger {
+	return &AuthLedger{
+		accounts:    map[string]*model.Account{},
+		assessments: map[string]*model.RiskAssessment{},
+		lastTxTimes: map[string]time.Time{},
+		authzer:     authorizer,
+		profiles:    profiles,
+	}
 }
 
 // AuthLedger is the implementation of the Ledger interface delegating to a
 // rule.Authorizer to authorize all the transactions. Not to be confused with
 // Heath Ledger actor.
 type AuthLedger struct {
-	accounts map[string]*model.Account
-	authzer  rule.Authorizer
+	accounts    map[string]*model.Account
+	assessments map[string]*model.RiskAssessment
+	lastTxTimes map[string]time.Time
+	authzer     rule.Authorizer
+	profiles    map[string]rule.Authorizer
+}
+
+// authorizerOf returns the authorizer registered for the given profile, or
+// false if there is no such profile. The empty profile is the default one.
+func (l *AuthLedger) authorizerOf(profile string) (rule.Authorizer, bool) {
+	if profile == "" {
+		return l.authzer, true
+	}
+	authzer, ok := l.profiles[profile]
+	return authzer, ok
 }
 
 // CreateAccount implements the Ledger interface. It currently only supports a
 // single account, so this can be called only once per ledger instance or an
 // account-already-initialized error will be returned.
+//
+// It also validates the configurations of the account, like its time zone,
+// returning a regular (fatal) error if any of them is not valid. An account
+// with a profile not registered in the ledger is not created either, but with
+// an unknown-profile violation instead.
 func (l *AuthLedger) CreateAccount(account model.Account) (*model.Account, error) {
 	id := account.ID
 	if existing := l.accounts[id]; existing != nil {
 		return existing.Copy(), violation.ErrorAccountAlreadyInitialized
 	}
+	if err := account.Validate(); err != nil {
+		return nil, violation.ErrorInvalidAccountConfig
+	}
+	if _, ok := l.authorizerOf(account.Profile); !ok {
+		return nil, violation.ErrorUnknownProfile
+	}
 
 	l.accounts[id] = &account
 	return account.Copy(), nil
 }
 
 // PerformTransaction implements the Ledger interface. It initially calls the
-// configured authorizer to ensure that the transaction is allowed and then
+// authorizer of the account profile to ensure that the transaction is allowed and then
 // performs it updating the current state of the account.
+//
+// The transactions of each account must be sent in chronological order, or a
+// regular (fatal) error is returned, regardless of the authorizer.
+//
+// If the transaction is not allowed, the actions of any rule.ActionError
+// returned by the authorizer are still applied to the account (e.g. blocking
+// its card). Any rule.ShadowErrors don't prevent the transaction from being
+// performed, being returned together with the actual errors (if any).
 func (l *AuthLedger) PerformTransaction(transaction model.Transaction) (*model.Account, error) {
+	return l.performTransaction(transaction, nil)
+}
+
+// RiskAssessment implements the Ledger interface, returning the assessment
+// taken from the authorizer of the account profile after its last transaction.
+func (l *AuthLedger) RiskAssessment(accountID string) *model.RiskAssessment {
+	return l.assessments[accountID]
+}
+
+// TraceTransaction implements the Ledger interface, recording the decisions of
+// the authorizer with rule.AuthorizeTraced.
+func (l *AuthLedger) TraceTransaction(transaction model.Transaction) (*model.Account, []model.RuleDecision, error) {
+	trace := &rule.Trace{}
+	account, err := l.performTransaction(transaction, trace)
+	return account, trace.Decisions, err
+}
+
+// performTransaction implements both PerformTransaction and TraceTransaction,
+// recording the decisions of the authorizer in the trace if it is not nil.
+func (l *AuthLedger) performTransaction(transaction model.Transaction, trace *rule.Trace) (*model.Account, error) {
 	account := l.accounts[transaction.AccountID]
 	if account == nil {
 		return nil, violation.ErrorAccountNotInitialized
 	}
+	if last := l.lastTxTimes[transaction.AccountID]; transaction.Time.Before(last) {
+		return account.Copy(), fmt.Errorf("Transactions must be sent in chronological order. Received %v after %v", transaction.Time, last)
+	}
+	l.lastTxTimes[transaction.AccountID] = transaction.Time
 
-	commitFunc, err := l.authzer.Authorize(*account, transaction)
-	if err != nil {
+	authzer, _ := l.authorizerOf(account.Profile)
+	var (
+		commitFunc rule.CommitFunc
+		err        error
+	)
+	if trace != nil {
+		commitFunc, err = rule.AuthorizeTraced(authzer, *account, transaction, trace)
+	} else {
+		commitFunc, err = authzer.Authorize(*account, transaction)
+	}
+	l.assessments[transaction.AccountID] = rule.TakeAssessment(transaction.AccountID, authzer)
+	declinedErr, shadowErr := rule.SplitShadow(err)
+	if declinedErr != nil {
+		for _, action := range rule.DeclineActions(declinedErr) {
+			action(account)
+		}
 		return account.Copy(), err
 	}
 
 	account.AvailableLimit -= transaction.Amount
 	if commitFunc != nil {
-		commitFunc()
+		commitFunc(account)
 	}
+	return account.Copy(), shadowErr
+}
+
+// UpdateCategoryControls implements the Ledger interface. The new controls are
+// only stored in the account, to be enforced by the configured authorizer.
+func (l *AuthLedger) UpdateCategoryControls(update model.CategoryControlsUpdate) (*model.Account, error) {
+	account := l.accounts[update.AccountID]
+	if account == nil {
+		return nil, violation.ErrorAccountNotInitialized
+	}
+
+	account.CategoryControls = update.Controls
+	return account.Copy(), nil
+}
+
+// UpdateMerchantAllowlist implements the Ledger interface. The new allowlist is
+// only stored in the account, to be enforced by the configured authorizer.
+func (l *AuthLedger) UpdateMerchantAllowlist(update model.MerchantListUpdate) (*model.Account, error) {
+	account := l.accounts[update.AccountID]
+	if account == nil {
+		return nil, violation.ErrorAccountNotInitialized
+	}
+
+	account.MerchantAllowlist = update.Merchants
+	return account.Copy(), nil
+}
+
+// UpdateMerchantBlocklist implements the Ledger interface. The new blocklist is
+// only stored in the account, to be enforced by the configured authorizer.
+func (l *AuthLedger) UpdateMerchantBlocklist(update model.MerchantListUpdate) (*model.Account, error) {
+	account := l.accounts[update.AccountID]
+	if account == nil {
+		return nil, violation.ErrorAccountNotInitialized
+	}
+
+	account.MerchantBlocklist = update.Merchants
+	return account.Copy(), nil
+}
+
+// UpdateSubscriptions implements the Ledger interface. The new subscriptions
+// are only stored in the account, to be considered by the configured
+// authorizer.
+func (l *AuthLedger) UpdateSubscriptions(update model.SubscriptionsUpdate) (*model.Account, error) {
+	account := l.accounts[update.AccountID]
+	if account == nil {
+		return nil, violation.ErrorAccountNotInitialized
+	}
+
+	account.Subscriptions = update.Subscriptions
+	return account.Copy(), nil
+}
+
+// UpdateProfile implements the Ledger interface. The account keeps its current
+// profile, with an unknown-profile violation, if the new one is not registered
+// in the ledger.
+func (l *AuthLedger) UpdateProfile(update model.ProfileUpdate) (*model.Account, error) {
+	account := l.accounts[update.AccountID]
+	if account == nil {
+		return nil, violation.ErrorAccountNotInitialized
+	}
+	if _, ok := l.authorizerOf(update.Profile); !ok {
+		return account.Copy(), violation.ErrorUnknownProfile
+	}
+
+	account.Profile = update.Profile
 	return account.Copy(), nil
 }
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"nuledger/authorizer"
 	"nuledger/authorizer/rule"
+	"nuledger/authorizer/rules"
 	mock_rule "nuledger/mocks/authorizer/rule"
 	"nuledger/model"
 	"nuledger/model/violation"
+	"nuledger/util"
 	"testing"
 	"time"
 
@@ -28,6 +30,13 @@ func TestLedger(t *testing.T) {
 		ledger := authorizer.NewLedger(authzer)
 
 		Convey("When no account has been created", func() {
+			Convey("It should return an error for any transaction traced", func() {
+				account, trace, err := ledger.TraceTransaction(dummyTransaction)
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+				So(trace, ShouldBeEmpty)
+			})
+
 			Convey("It should return an error for any transaction perform", func() {
 				account, err := ledger.PerformTransaction(dummyTransaction)
 				So(err, ShouldNotBeNil)
@@ -38,6 +47,40 @@ func TestLedger(t *testing.T) {
 				So(verr.Code, ShouldEqual, violation.AccountNotInitialized)
 			})
 
+			Convey("It should return an error for updating category controls", func() {
+				account, err := ledger.UpdateCategoryControls(model.CategoryControlsUpdate{})
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+			})
+
+			Convey("It should return an error for updating merchant lists", func() {
+				account, err := ledger.UpdateMerchantAllowlist(model.MerchantListUpdate{})
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+
+				account, err = ledger.UpdateMerchantBlocklist(model.MerchantListUpdate{})
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+			})
+
+			Convey("It should return an error for updating subscriptions", func() {
+				account, err := ledger.UpdateSubscriptions(model.SubscriptionsUpdate{})
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+			})
+
+			Convey("It should return an error for updating the profile", func() {
+				account, err := ledger.UpdateProfile(model.ProfileUpdate{})
+				So(err, ShouldResemble, violation.ErrorAccountNotInitialized)
+				So(account, ShouldBeNil)
+			})
+
+			Convey("It should return an error for creating an account with an unknown profile", func() {
+				account, err := ledger.CreateAccount(model.Account{ActiveCard: true, Profile: "premium"})
+				So(err, ShouldResemble, violation.ErrorUnknownProfile)
+				So(account, ShouldBeNil)
+			})
+
 			Convey("It should allow creating an account", func() {
 				accountReq := model.Account{ActiveCard: true, AvailableLimit: 2}
 
@@ -46,6 +89,22 @@ func TestLedger(t *testing.T) {
 				So(account, ShouldNotBeNil)
 				So(*account, ShouldResemble, accountReq)
 			})
+
+			Convey("It should return a violation for an invalid time zone", func() {
+				accountReq := model.Account{ActiveCard: true, TimeZone: "Nowhere/Atlantis"}
+
+				account, err := ledger.CreateAccount(accountReq)
+				So(err, ShouldResemble, violation.ErrorInvalidAccountConfig)
+				So(account, ShouldBeNil)
+			})
+
+			Convey("It should return a violation for an invalid schedule", func() {
+				accountReq := model.Account{ActiveCard: true, Schedule: &model.Schedule{Holidays: []string{"25/12/2021"}}}
+
+				account, err := ledger.CreateAccount(accountReq)
+				So(err, ShouldResemble, violation.ErrorInvalidAccountConfig)
+				So(account, ShouldBeNil)
+			})
 		})
 
 		Convey("When there is an account created", func() {
@@ -65,6 +124,62 @@ func TestLedger(t *testing.T) {
 				So(verr.Code, ShouldEqual, violation.AccountAlreadyInitialized)
 			})
 
+			Convey("It should update its category controls", func() {
+				controls := map[model.Category]model.CategoryControl{model.CategoryFuel: {MaxAmount: 200}}
+				expected := initAccountState
+				expected.CategoryControls = controls
+
+				account, err := ledger.UpdateCategoryControls(model.CategoryControlsUpdate{Controls: controls})
+				So(err, ShouldBeNil)
+				So(account, ShouldNotBeNil)
+				So(*account, ShouldResemble, expected)
+
+				Convey("And authorize further transactions with them", func() {
+					authzer.EXPECT().
+						Authorize(gomock.Eq(expected), gomock.Eq(dummyTransaction)).
+						Return(nil, nil)
+					_, err := ledger.PerformTransaction(dummyTransaction)
+					So(err, ShouldBeNil)
+				})
+			})
+
+			Convey("It should update its merchant lists", func() {
+				expected := initAccountState
+				expected.MerchantAllowlist = []string{"Uber*"}
+				expected.MerchantBlocklist = []string{"Uber Eats"}
+
+				_, err := ledger.UpdateMerchantAllowlist(model.MerchantListUpdate{Merchants: expected.MerchantAllowlist})
+				So(err, ShouldBeNil)
+				account, err := ledger.UpdateMerchantBlocklist(model.MerchantListUpdate{Merchants: expected.MerchantBlocklist})
+				So(err, ShouldBeNil)
+				So(account, ShouldNotBeNil)
+				So(*account, ShouldResemble, expected)
+			})
+
+			Convey("It should update its subscriptions", func() {
+				expected := initAccountState
+				expected.Subscriptions = []model.Subscription{{ID: "sub", Merchant: "Streaming Co", MaxAmount: 30}}
+
+				account, err := ledger.UpdateSubscriptions(model.SubscriptionsUpdate{Subscriptions: expected.Subscriptions})
+				So(err, ShouldBeNil)
+				So(account, ShouldNotBeNil)
+				So(*account, ShouldResemble, expected)
+			})
+
+			Convey("It should trace the decision of the authorizer", func() {
+				authzer.EXPECT().
+					Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
+					Return(nil, violation.ErrorInsufficientLimit)
+
+				account, trace, err := ledger.TraceTransaction(dummyTransaction)
+				So(err, ShouldResemble, violation.ErrorInsufficientLimit)
+				So(*account, ShouldResemble, initAccountState)
+				So(trace, ShouldHaveLength, 1)
+				So(trace[0].Rule, ShouldEqual, "mock_rule.MockAuthorizer")
+				So(trace[0].Outcome, ShouldEqual, model.RuleOutcomeFail)
+				So(trace[0].Violations, ShouldResemble, []violation.Code{violation.InsufficientLimit})
+			})
+
 			Convey("It should check transactions with authorizer", func() {
 				authzer.EXPECT().
 					Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
@@ -93,11 +208,19 @@ func TestLedger(t *testing.T) {
 				})
 				Convey("It should call returned commitFunc", func() {
 					callCount := 0
-					commit := func() { callCount++ }
+					commit := func(_ *model.Account) { callCount++ }
 
 					test(commit)
 					So(callCount, ShouldEqual, 1)
 				})
+				Convey("It should allow commitFunc to update the account state", func() {
+					expectedAfterTx := initAccountState
+					expectedAfterTx.AvailableLimit -= dummyTransaction.Amount + 1
+					commit := func(account *model.Account) { account.AvailableLimit-- }
+
+					account := test(commit)
+					So(account, ShouldResemble, expectedAfterTx)
+				})
 			})
 
 			Convey("When authorizer returns an error", func() {
@@ -123,13 +246,206 @@ func TestLedger(t *testing.T) {
 				})
 				Convey("It should NOT call the commit function", func() {
 					callCount := 0
-					commit := func() { callCount++ }
+					commit := func(_ *model.Account) { callCount++ }
 
 					test(commit)
 					So(callCount, ShouldEqual, 0)
 				})
 			})
 
+			Convey("When authorizer returns only shadow errors", func() {
+				shadowErr := rule.ShadowError{Err: errors.New("Custom error")}
+				callCount := 0
+				commit := func(_ *model.Account) { callCount++ }
+				authzer.EXPECT().
+					Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
+					Return(commit, shadowErr)
+
+				account, err := ledger.PerformTransaction(dummyTransaction)
+
+				Convey("It should still perform the transaction", func() {
+					So(account.AvailableLimit, ShouldEqual, initAccountState.AvailableLimit-dummyTransaction.Amount)
+					So(callCount, ShouldEqual, 1)
+				})
+				Convey("It should propagate the shadow errors", func() {
+					So(err, ShouldResemble, shadowErr)
+				})
+			})
+
+			Convey("When authorizer returns an action error", func() {
+				reason := errors.New("Custom error")
+				returnedErr := rule.ActionError{
+					Err:    reason,
+					Action: func(account *model.Account) { account.ActiveCard = false },
+				}
+				authzer.EXPECT().
+					Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
+					Return(nil, returnedErr)
+
+				account, err := ledger.PerformTransaction(dummyTransaction)
+
+				Convey("It should propagate the error", func() {
+					So(errors.Is(err, reason), ShouldBeTrue)
+				})
+				Convey("It should apply the action without performing the transaction", func() {
+					So(account.ActiveCard, ShouldBeFalse)
+					So(account.AvailableLimit, ShouldEqual, initAccountState.AvailableLimit)
+				})
+			})
+
+		})
+	})
+}
+
+func TestLedgerShadowRules(t *testing.T) {
+	Convey("Given an authorizer Ledger with a rule in shadow mode before others", t, func() {
+		shadowed := rule.AuthorizerFunc(func(_ model.Account, _ model.Transaction) (rule.CommitFunc, error) {
+			return nil, violation.ErrorSpendLimitExceeded
+		})
+		ledger := authorizer.NewLedger(rule.FirstFailure{rule.NewShadow("shadowed", shadowed), rule.AuthorizerFunc(rules.SufficientLimit)})
+		_, err := ledger.CreateAccount(model.Account{ActiveCard: true, AvailableLimit: 10})
+		So(err, ShouldBeNil)
+
+		Convey("It should still apply the other rules", func() {
+			transaction := dummyTransaction
+			transaction.Amount = 1000
+			account, err := ledger.PerformTransaction(transaction)
+			declined, shadow := rule.SplitShadow(err)
+			So(errors.Is(declined, violation.ErrorInsufficientLimit), ShouldBeTrue)
+			So(errors.Is(shadow, violation.ErrorSpendLimitExceeded), ShouldBeTrue)
+			So(account.AvailableLimit, ShouldEqual, 10)
+		})
+	})
+}
+
+func TestLedgerRiskAssessment(t *testing.T) {
+	Convey("Given an authorizer Ledger with a risk score rule", t, func() {
+		weights := map[model.RiskSignal]float64{model.RiskSignalNewMerchant: 10}
+		ledger := authorizer.NewLedger(rules.NewRiskScore(weights, 15))
+		_, err := ledger.CreateAccount(model.Account{ActiveCard: true, AvailableLimit: 500})
+		So(err, ShouldBeNil)
+
+		Convey("It should have no assessment before any transactions", func() {
+			So(ledger.RiskAssessment(""), ShouldBeNil)
+		})
+
+		Convey("It should keep the assessment of the last transaction performed", func() {
+			_, err := ledger.PerformTransaction(dummyTransaction)
+			So(err, ShouldBeNil)
+			expected := &model.RiskAssessment{Score: 10, Signals: []model.RiskSignal{model.RiskSignalNewMerchant}}
+			So(ledger.RiskAssessment(""), ShouldResemble, expected)
+			So(ledger.RiskAssessment("other"), ShouldBeNil)
+
+			_, err = ledger.PerformTransaction(dummyTransaction)
+			So(err, ShouldBeNil)
+			So(ledger.RiskAssessment(""), ShouldResemble, &model.RiskAssessment{Signals: []model.RiskSignal{}})
+		})
+	})
+}
+
+func TestProfileLedger(t *testing.T) {
+	Convey("Given an authorizer Ledger with profiles", t, func() {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		defaultAuthzer := mock_rule.NewMockAuthorizer(ctrl)
+		premiumAuthzer := mock_rule.NewMockAuthorizer(ctrl)
+		ledger := authorizer.NewProfileLedger(defaultAuthzer, map[string]rule.Authorizer{"premium": premiumAuthzer})
+
+		Convey("It should authorize transactions with the authorizer of the account profile", func() {
+			initAccountState := model.Account{Profile: "premium", ActiveCard: true, AvailableLimit: 500}
+			_, err := ledger.CreateAccount(initAccountState)
+			So(err, ShouldBeNil)
+
+			premiumAuthzer.EXPECT().
+				Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
+				Return(nil, nil)
+			_, err = ledger.PerformTransaction(dummyTransaction)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("When an account has been created without a profile", func() {
+			initAccountState := model.Account{ActiveCard: true, AvailableLimit: 500}
+			_, err := ledger.CreateAccount(initAccountState)
+			So(err, ShouldBeNil)
+
+			Convey("It should authorize transactions with the default authorizer", func() {
+				defaultAuthzer.EXPECT().
+					Authorize(gomock.Eq(initAccountState), gomock.Eq(dummyTransaction)).
+					Return(nil, nil)
+				_, err := ledger.PerformTransaction(dummyTransaction)
+				So(err, ShouldBeNil)
+			})
+
+			Convey("It should switch its profile and authorize further transactions with it", func() {
+				expected := initAccountState
+				expected.Profile = "premium"
+
+				account, err := ledger.UpdateProfile(model.ProfileUpdate{Profile: "premium"})
+				So(err, ShouldBeNil)
+				So(*account, ShouldResemble, expected)
+
+				premiumAuthzer.EXPECT().
+					Authorize(gomock.Eq(expected), gomock.Eq(dummyTransaction)).
+					Return(nil, nil)
+				_, err = ledger.PerformTransaction(dummyTransaction)
+				So(err, ShouldBeNil)
+			})
+
+			Convey("It should NOT switch to an unknown profile", func() {
+				account, err := ledger.UpdateProfile(model.ProfileUpdate{Profile: "corporate"})
+				So(err, ShouldResemble, violation.ErrorUnknownProfile)
+				So(*account, ShouldResemble, initAccountState)
+			})
+		})
+
+		Convey("Each profile should keep the state of its own rules", func() {
+			newFrequency := func() rule.Authorizer {
+				limiter := util.RateLimiter{MaxEvents: 1, Interval: time.Hour}
+				keyMapper := func(tx *model.Transaction) interface{} { return tx.AccountID }
+				return rules.NewFrequencyAnalyzer(limiter, keyMapper, violation.ErrorHighFrequencySmallInterval)
+			}
+			ledger := authorizer.NewProfileLedger(newFrequency(), map[string]rule.Authorizer{"premium": newFrequency()})
+			_, err := ledger.CreateAccount(model.Account{ActiveCard: true, AvailableLimit: 500})
+			So(err, ShouldBeNil)
+			perform := func(diff time.Duration) error {
+				transaction := dummyTransaction
+				transaction.Time = ledgerStartTime.Add(diff)
+				_, err := ledger.PerformTransaction(transaction)
+				return err
+			}
+
+			So(perform(0), ShouldBeNil)
+			So(errors.Is(perform(1*time.Minute), violation.ErrorHighFrequencySmallInterval), ShouldBeTrue)
+
+			_, err = ledger.UpdateProfile(model.ProfileUpdate{Profile: "premium"})
+			So(err, ShouldBeNil)
+			So(perform(2*time.Minute), ShouldBeNil)
+
+			_, err = ledger.UpdateProfile(model.ProfileUpdate{})
+			So(err, ShouldBeNil)
+			So(errors.Is(perform(3*time.Minute), violation.ErrorHighFrequencySmallInterval), ShouldBeTrue)
+		})
+
+		Convey("It should enforce the chronological order of the transactions across profiles", func() {
+			ledger := authorizer.NewProfileLedger(&rules.ChronologicalOrder{}, map[string]rule.Authorizer{"premium": &rules.ChronologicalOrder{}})
+			_, err := ledger.CreateAccount(model.Account{ActiveCard: true, AvailableLimit: 500})
+			So(err, ShouldBeNil)
+			perform := func(diff time.Duration) error {
+				transaction := dummyTransaction
+				transaction.Time = ledgerStartTime.Add(diff)
+				_, err := ledger.PerformTransaction(transaction)
+				return err
+			}
+
+			So(perform(4*time.Hour), ShouldBeNil)
+
+			_, err = ledger.UpdateProfile(model.ProfileUpdate{Profile: "premium"})
+			So(err, ShouldBeNil)
+			err = perform(0)
+			So(err, ShouldNotBeNil)
+			So(errors.As(err, &violation.Error{}), ShouldBeFalse)
+			So(perform(5*time.Hour), ShouldBeNil)
 		})
 	})
 }
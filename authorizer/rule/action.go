@@ -0,0 +1,48 @@
+package rule
+
+import (
+	"errors"
+	"nuledger/util"
+)
+
+// ActionError is an error that can be returned by an Authorizer to request some
+// action to be performed on the account even though the transaction is not
+// executed, like blocking the card of the account when fraud is detected.
+//
+// The wrapped Err is the actual reason for declining the transaction, usually
+// a violation.Error, while the Action is called by the transaction execution
+// agent with the account state instead of any CommitFunc.
+type ActionError struct {
+	Err    error
+	Action CommitFunc
+}
+
+// Error implements the error interface, returning the wrapped error message.
+func (e ActionError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error, so that it can be inspected with the
+// standard errors package functions.
+func (e ActionError) Unwrap() error {
+	return e.Err
+}
+
+// DeclineActions returns the actions of all the ActionErrors represented by the
+// given error, including the ones inside an util.AggregateError.
+func DeclineActions(err error) []CommitFunc {
+	errs := []error{err}
+	var aggErr util.AggregateError
+	if errors.As(err, &aggErr) {
+		errs = aggErr.Errors
+	}
+
+	var actions []CommitFunc
+	for _, innerErr := range errs {
+		var actionErr ActionError
+		if errors.As(innerErr, &actionErr) && actionErr.Action != nil {
+			actions = append(actions, actionErr.Action)
+		}
+	}
+	return actions
+}
@@ -0,0 +1,49 @@
+package rule
+
+import (
+	"nuledger/model"
+)
+
+// Assessor is implemented by the authorizers that assess the risk of the
+// transactions they authorize, so that the assessment can be reported even for
+// the transactions they allow, without being returned as an error.
+type Assessor interface {
+	// TakeAssessment returns the assessment of the last transaction of the
+	// account authorized by the rule, whether allowed or not, and forgets it so
+	// that it's only reported once. It returns nil if the rule didn't authorize
+	// any transactions of the account since the last call.
+	TakeAssessment(accountID string) *model.RiskAssessment
+}
+
+// TakeAssessment takes the assessments of the given account from all of the
+// given authorizers that are Assessors, including the ones inside a List,
+// FirstFailure, When, Named or Rollout authorizer, and returns the first one
+// found, or nil if there is none. Rules in shadow mode are not considered, as
+// they must not change the output of the transactions.
+func TakeAssessment(accountID string, authzers ...Authorizer) *model.RiskAssessment {
+	var assessment *model.RiskAssessment
+	keep := func(taken *model.RiskAssessment) {
+		if assessment == nil {
+			assessment = taken
+		}
+	}
+	for _, authzer := range authzers {
+		switch authzer := authzer.(type) {
+		case Assessor:
+			keep(authzer.TakeAssessment(accountID))
+		case List:
+			keep(TakeAssessment(accountID, authzer...))
+		case FirstFailure:
+			keep(TakeAssessment(accountID, authzer...))
+		case *conditional:
+			keep(TakeAssessment(accountID, authzer.authzer))
+		case Named:
+			keep(TakeAssessment(accountID, authzer.Authorizer))
+		case *Named:
+			keep(TakeAssessment(accountID, authzer.Authorizer))
+		case *Rollout:
+			keep(TakeAssessment(accountID, authzer.treatment, authzer.control))
+		}
+	}
+	return assessment
+}
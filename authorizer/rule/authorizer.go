@@ -26,4 +26,8 @@ type Authorizer interface {
 // to update the internal state of the Authorizer so as to guarantee future
 // authorizations are performed consistently, considering only the actually
 // executed transactions, not the attempted ones.
-type CommitFunc func()
+//
+// It receives the account state being updated by the transaction, already
+// debited from the transaction amount, so that it can also apply any further
+// changes required by the rule (e.g. charging some fee from the account).
+type CommitFunc func(account *model.Account)
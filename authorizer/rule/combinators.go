@@ -0,0 +1,187 @@
+package rule
+
+import (
+	"errors"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// Predicate is a condition on the account and transaction being authorized,
+// used to decide whether some rule applies to them.
+type Predicate func(account model.Account, transaction model.Transaction) bool
+
+// FirstFailure is an alternative to List which calls the authorizers in order
+// only until one of them fails, returning that single error. The following
+// authorizers are not called at all, so they don't record the transaction.
+// Authorizers returning only ShadowErrors don't fail, and their ShadowErrors
+// are returned together with the result of the others.
+type FirstFailure []Authorizer
+
+// Ensure FirstFailure implements the Authorizer and Tracer interfaces
+var (
+	_ Authorizer = FirstFailure(nil)
+	_ Tracer     = FirstFailure(nil)
+)
+
+// Authorize implements the Authorizer interface. The returned CommitFunc calls
+// the commit functions of all the authorizers, which are only called if none
+// of them failed.
+func (f FirstFailure) Authorize(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+	return f.AuthorizeTraced(account, transaction, nil)
+}
+
+// AuthorizeTraced implements the Tracer interface, authorizing like Authorize
+// while recording the decision of each authorizer in the trace, if not nil.
+// The authorizers after the first failure are recorded as skipped.
+func (f FirstFailure) AuthorizeTraced(account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error) {
+	var (
+		commitFuncs = make([]CommitFunc, 0, len(f))
+		shadowErrs  []error
+	)
+	for i, rule := range f {
+		commit, err := authorize(rule, account, transaction, trace)
+		declined, shadow := SplitShadow(err)
+		shadowErrs = append(shadowErrs, flatten(shadow)...)
+		if declined != nil {
+			if trace != nil {
+				trace.skip(f[i+1:]...)
+			}
+			return nil, util.AggregateErrors(append(shadowErrs, flatten(declined)...))
+		}
+		if commit != nil {
+			commitFuncs = append(commitFuncs, commit)
+		}
+	}
+	return combine(commitFuncs), util.AggregateErrors(shadowErrs)
+}
+
+// All creates an authorizer which allows the transaction only if all of the
+// given authorizers allow it, short-circuiting on the first one that doesn't
+// like FirstFailure. In that case, it returns the given violation error, or the
+// error of the failing authorizer itself if the violation is nil. The decline
+// actions of the failing authorizer are kept in either case, with the violation
+// being wrapped in an ActionError if it has any. ShadowErrors don't fail, being
+// returned as they are like in FirstFailure.
+//
+// Fatal errors (i.e. not violations) are always propagated as they are.
+func All(violationErr error, authzers ...Authorizer) Authorizer {
+	first := FirstFailure(authzers)
+	return AuthorizerFunc(func(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+		commit, err := first.Authorize(account, transaction)
+		declined, shadow := SplitShadow(err)
+		if declined != nil && violationErr != nil && isViolation(declined) {
+			return nil, util.AggregateErrors(append(flatten(shadow), replaceViolation(violationErr, declined)))
+		}
+		return commit, err
+	})
+}
+
+// Any creates an authorizer which allows the transaction if any of the given
+// authorizers allows it, short-circuiting on the first one that does. Only the
+// commit function of that authorizer is returned, since the failing ones did
+// not apply to the transaction.
+//
+// If none of them allows the transaction, it returns the given violation error
+// or all of their errors aggregated if the violation is nil, keeping their
+// decline actions like All. ShadowErrors don't fail, being returned together
+// with the result of the authorizers. Fatal errors (i.e. not violations) are
+// always propagated as they are.
+func Any(violationErr error, authzers ...Authorizer) Authorizer {
+	return AuthorizerFunc(func(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+		var errs, shadowErrs []error
+		for _, rule := range authzers {
+			commit, err := rule.Authorize(account, transaction)
+			declined, shadow := SplitShadow(err)
+			shadowErrs = append(shadowErrs, flatten(shadow)...)
+			if declined == nil {
+				return commit, util.AggregateErrors(shadowErrs)
+			}
+			if !isViolation(declined) {
+				return nil, declined
+			}
+			errs = append(errs, flatten(declined)...)
+		}
+		if violationErr != nil {
+			errs = []error{replaceViolation(violationErr, util.AggregateErrors(errs))}
+		}
+		return nil, util.AggregateErrors(append(shadowErrs, errs...))
+	})
+}
+
+// Not creates an authorizer which inverts the given one, returning the given
+// violation error if it allows the transaction and allowing the transaction if
+// it returns any violation. The inverted authorizer never has its commit
+// function (or decline actions) called, as it doesn't allow the transaction in
+// any case it is actually executed. ShadowErrors are not violations to be
+// inverted, so the authorizer returning only them is considered to allow the
+// transaction, and they are dropped.
+//
+// Fatal errors (i.e. not violations) are propagated as they are.
+func Not(violationErr error, authzer Authorizer) Authorizer {
+	return AuthorizerFunc(func(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+		_, err := authzer.Authorize(account, transaction)
+		declined, _ := SplitShadow(err)
+		if declined == nil {
+			return nil, violationErr
+		}
+		if !isViolation(declined) {
+			return nil, declined
+		}
+		return nil, nil
+	})
+}
+
+// replaceViolation returns the violation error to be returned by a combinator
+// instead of the given error of its inner authorizers, wrapped in an ActionError
+// with their decline actions if they have any.
+func replaceViolation(violationErr, err error) error {
+	actions := DeclineActions(err)
+	if len(actions) == 0 {
+		return violationErr
+	}
+	return ActionError{Err: violationErr, Action: combine(actions)}
+}
+
+// When creates an authorizer which applies the given one only to the accounts
+// and transactions matching the predicate, allowing any others without calling
+// it at all (and recording it as skipped in traces).
+func When(predicate Predicate, authzer Authorizer) Authorizer {
+	return &conditional{predicate: predicate, authzer: authzer}
+}
+
+// conditional is the authorizer created by When.
+type conditional struct {
+	predicate Predicate
+	authzer   Authorizer
+}
+
+func (c *conditional) Authorize(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+	return c.AuthorizeTraced(account, transaction, nil)
+}
+
+func (c *conditional) AuthorizeTraced(account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error) {
+	if !c.predicate(account, transaction) {
+		if trace != nil {
+			trace.skip(c.authzer)
+		}
+		return nil, nil
+	}
+	return authorize(c.authzer, account, transaction, trace)
+}
+
+// isViolation returns whether the error represents only violations of rules,
+// as opposed to fatal errors, including all the ones inside an
+// util.AggregateError.
+func isViolation(err error) bool {
+	var aggErr util.AggregateError
+	if !errors.As(err, &aggErr) {
+		return errors.As(err, &violation.Error{})
+	}
+	for _, innerErr := range aggErr.Errors {
+		if !isViolation(innerErr) {
+			return false
+		}
+	}
+	return true
+}
@@ -9,20 +9,29 @@ import (
 // it were a single Authorizer.
 type List []Authorizer
 
-// Ensure List implements the Authorizer interface
-var _ Authorizer = List(nil)
+// Ensure List implements the Authorizer and Tracer interfaces
+var (
+	_ Authorizer = List(nil)
+	_ Tracer     = List(nil)
+)
 
 // Authorize function from List type calls every authorizer in the slice and
 // combines both the returned commit functions into a single CommitFunc which
 // calls all of the original ones, and the returned errors into a possible
 // util.AggregateError in case multiple errors were returned.
 func (l List) Authorize(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+	return l.AuthorizeTraced(account, transaction, nil)
+}
+
+// AuthorizeTraced implements the Tracer interface, authorizing like Authorize
+// while recording the decision of each authorizer in the trace, if not nil.
+func (l List) AuthorizeTraced(account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error) {
 	var (
 		commitFuncs = make([]CommitFunc, 0, 5)
 		errs        []error
 	)
 	for _, rule := range l {
-		commit, err := rule.Authorize(account, transaction)
+		commit, err := authorize(rule, account, transaction, trace)
 		if commit != nil {
 			commitFuncs = append(commitFuncs, commit)
 		}
@@ -34,9 +43,18 @@ func (l List) Authorize(account model.Account, transaction model.Transaction) (C
 }
 
 func combine(funcs []CommitFunc) CommitFunc {
-	return func() {
+	return func(account *model.Account) {
 		for _, f := range funcs {
-			f()
+			f(account)
 		}
 	}
 }
+
+// authorize calls the authorizer recording its decisions in the trace, if not
+// nil.
+func authorize(authzer Authorizer, account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error) {
+	if trace == nil {
+		return authzer.Authorize(account, transaction)
+	}
+	return AuthorizeTraced(authzer, account, transaction, trace)
+}
@@ -0,0 +1,102 @@
+package rule
+
+import (
+	"errors"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// Metrics are the counters of the outcomes of a rule, identified by its name,
+// for analyzing how it behaves (e.g. what it would decline before enabling it).
+type Metrics struct {
+	Rule string `json:"rule"`
+	// Cohort is the group of accounts the metrics are restricted to, if any.
+	Cohort string `json:"cohort,omitempty"`
+	// Evaluated is the number of transactions authorized by the rule.
+	Evaluated int `json:"evaluated"`
+	// Declined is the number of transactions for which the rule returned any
+	// violations.
+	Declined int `json:"declined"`
+	// Violations is the number of times each violation code was returned.
+	Violations map[violation.Code]int `json:"violations,omitempty"`
+}
+
+// DeclineRate returns the fraction of the evaluated transactions which the rule
+// declined, or zero if it hasn't evaluated any.
+func (m Metrics) DeclineRate() float64 {
+	if m.Evaluated == 0 {
+		return 0
+	}
+	return float64(m.Declined) / float64(m.Evaluated)
+}
+
+// MetricsReporter is implemented by the authorizers that keep metrics about
+// their rules.
+type MetricsReporter interface {
+	RuleMetrics() []Metrics
+}
+
+// CollectMetrics returns the metrics of all the given authorizers that are
+// MetricsReporters, including the ones inside a List or FirstFailure.
+func CollectMetrics(authzers ...Authorizer) []Metrics {
+	var metrics []Metrics
+	for _, authzer := range authzers {
+		switch authzer := authzer.(type) {
+		case MetricsReporter:
+			metrics = append(metrics, authzer.RuleMetrics()...)
+		case List:
+			metrics = append(metrics, CollectMetrics(authzer...)...)
+		case FirstFailure:
+			metrics = append(metrics, CollectMetrics(authzer...)...)
+		}
+	}
+	return metrics
+}
+
+// record counts an authorization of the rule with the returned error, which
+// must be either nil or a violation.
+func (m *Metrics) record(err error) {
+	m.Evaluated++
+	if err == nil {
+		return
+	}
+	m.Declined++
+	if m.Violations == nil {
+		m.Violations = map[violation.Code]int{}
+	}
+	for _, code := range violationCodes(err) {
+		m.Violations[code]++
+	}
+}
+
+// copy returns a copy of the metrics not sharing the violation counters, so the
+// metrics can be reported while they are still being recorded.
+func (m Metrics) copy() Metrics {
+	if m.Violations != nil {
+		violations := make(map[violation.Code]int, len(m.Violations))
+		for code, count := range m.Violations {
+			violations[code] = count
+		}
+		m.Violations = violations
+	}
+	return m
+}
+
+// violationCodes returns the codes of all the violations represented by the
+// error, including the ones inside an util.AggregateError.
+func violationCodes(err error) []violation.Code {
+	errs := []error{err}
+	var aggErr util.AggregateError
+	if errors.As(err, &aggErr) {
+		errs = aggErr.Errors
+	}
+
+	var codes []violation.Code
+	for _, innerErr := range errs {
+		var verr violation.Error
+		if errors.As(innerErr, &verr) {
+			codes = append(codes, verr.Code)
+		}
+	}
+	return codes
+}
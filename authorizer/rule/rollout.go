@@ -0,0 +1,108 @@
+package rule
+
+import (
+	"hash/fnv"
+	"nuledger/model"
+)
+
+// Names of the cohorts of accounts in a Rollout, as reported in its metrics.
+const (
+	CohortTreatment = "treatment"
+	CohortControl   = "control"
+)
+
+// rolloutBuckets is the number of buckets accounts are hashed into, allowing
+// percentages with 2 decimal places.
+const rolloutBuckets = 10000
+
+// Rollout is an authorizer applying some rule (the treatment) to only a
+// percentage of the accounts, and optionally another rule (the control) to the
+// rest of them, so that a rule or new thresholds can be gradually ramped up.
+//
+// The cohort of each account is chosen by a stable hash of its ID together with
+// the name of the rollout, so an account is always in the same cohort of a
+// rollout (and increasing its percentage only moves accounts from the control
+// to the treatment cohort), while different rollouts pick different accounts.
+//
+// The outcomes of each cohort are counted in separate metrics, for comparing
+// their decline rates.
+type Rollout struct {
+	name       string
+	percentage float64
+	treatment  Authorizer
+	control    Authorizer
+	metrics    map[string]*Metrics
+}
+
+// Ensure Rollout implements the Authorizer and MetricsReporter interfaces
+var (
+	_ Authorizer      = &Rollout{}
+	_ MetricsReporter = &Rollout{}
+)
+
+// NewRollout creates a Rollout authorizer identified by the given name, which
+// applies the treatment rule to the given percentage (from 0 to 100) of the
+// accounts and the control rule to the others. The control can be nil for
+// allowing the transactions of the other accounts without any rule.
+func NewRollout(name string, percentage float64, treatment, control Authorizer) *Rollout {
+	return &Rollout{
+		name:       name,
+		percentage: percentage,
+		treatment:  treatment,
+		control:    control,
+		metrics: map[string]*Metrics{
+			CohortTreatment: {Rule: name, Cohort: CohortTreatment},
+			CohortControl:   {Rule: name, Cohort: CohortControl},
+		},
+	}
+}
+
+// Cohort returns the name of the cohort of the account with the given ID.
+func (r *Rollout) Cohort(accountID string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(r.name))
+	hash.Write([]byte{0})
+	hash.Write([]byte(accountID))
+
+	bucket := hash.Sum32() % rolloutBuckets
+	if float64(bucket) < r.percentage*rolloutBuckets/100 {
+		return CohortTreatment
+	}
+	return CohortControl
+}
+
+// Authorize implements the Authorizer interface, calling the rule of the cohort
+// of the account. Fatal errors (i.e. not violations) of the rules are
+// propagated without being counted in the metrics.
+func (r *Rollout) Authorize(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+	cohort := r.Cohort(account.ID)
+	authzer := r.treatment
+	if cohort == CohortControl {
+		authzer = r.control
+	}
+
+	var (
+		commit CommitFunc
+		err    error
+	)
+	if authzer != nil {
+		commit, err = authzer.Authorize(account, transaction)
+	}
+	if err != nil && !isViolation(err) {
+		return nil, err
+	}
+	r.metrics[cohort].record(err)
+	return commit, err
+}
+
+// RuleName implements the RuleNamer interface, returning the name of the
+// rollout.
+func (r *Rollout) RuleName() string {
+	return r.name
+}
+
+// RuleMetrics implements the MetricsReporter interface, returning the metrics
+// of the treatment and control cohorts, in this order.
+func (r *Rollout) RuleMetrics() []Metrics {
+	return []Metrics{r.metrics[CohortTreatment].copy(), r.metrics[CohortControl].copy()}
+}
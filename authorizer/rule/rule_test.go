@@ -2,9 +2,11 @@ package rule_test
 
 import (
 	"errors"
+	"fmt"
 	"nuledger/authorizer/rule"
 	mock_rule "nuledger/mocks/authorizer/rule"
 	"nuledger/model"
+	"nuledger/model/violation"
 	"nuledger/util"
 	"testing"
 	"time"
@@ -83,7 +85,7 @@ func TestRuleList(t *testing.T) {
 				commitFunc := rule.CommitFunc(nil)
 				if i%2 == 0 {
 					i := i // necessary for closure of separate variable
-					commitFunc = func() { callCounts[i]++ }
+					commitFunc = func(_ *model.Account) { callCounts[i]++ }
 				}
 				authzer.EXPECT().
 					Authorize(gomock.Eq(dummyAccount), gomock.Eq(dummyTransaction)).
@@ -99,13 +101,408 @@ func TestRuleList(t *testing.T) {
 			Convey("Returned commit function should call all of the internal ones", func() {
 				expectedCallCounts := []int{1, 0, 1, 0, 1}
 
-				commitFunc()
+				commitFunc(&dummyAccount)
 				So(callCounts, ShouldResemble, expectedCallCounts)
 			})
 		})
 	})
 }
 
+func TestDeclineActions(t *testing.T) {
+	Convey("Given some action errors", t, func() {
+		callCount := 0
+		action := func(_ *model.Account) { callCount++ }
+		reason := errors.New("Declined")
+		actionErr := rule.ActionError{Err: reason, Action: action}
+
+		Convey("They should wrap the decline reason", func() {
+			So(actionErr.Error(), ShouldEqual, reason.Error())
+			So(errors.Is(actionErr, reason), ShouldBeTrue)
+		})
+
+		Convey("It should return no actions for regular errors", func() {
+			So(rule.DeclineActions(nil), ShouldBeEmpty)
+			So(rule.DeclineActions(reason), ShouldBeEmpty)
+		})
+
+		Convey("It should return the action of a single error", func() {
+			actions := rule.DeclineActions(actionErr)
+			So(actions, ShouldHaveLength, 1)
+
+			actions[0](&model.Account{})
+			So(callCount, ShouldEqual, 1)
+		})
+
+		Convey("It should return the actions of aggregated errors", func() {
+			err := util.AggregateError{Errors: []error{actionErr, reason, actionErr}}
+			for _, action := range rule.DeclineActions(err) {
+				action(&model.Account{})
+			}
+			So(callCount, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestCombinators(t *testing.T) {
+	Convey("Given some authorizers", t, func() {
+		violationErr := violation.NewError("custom-violation", "Custom violation")
+		innerErr := violation.NewError("inner-violation", "Inner violation")
+		fatalErr := errors.New("Fatal error")
+
+		passing, other, failing, fatal := newFakeRule(nil), newFakeRule(nil), newFakeRule(innerErr), newFakeRule(fatalErr)
+
+		Convey("FirstFailure should stop on the first failure", func() {
+			So(authorizeAndCommit(rule.FirstFailure{passing, failing, other}), ShouldResemble, innerErr)
+			So(other.calls, ShouldEqual, 0)
+			So(passing.commits, ShouldEqual, 0)
+		})
+		Convey("FirstFailure should commit all the rules if none fails", func() {
+			So(authorizeAndCommit(rule.FirstFailure{passing, other}), ShouldBeNil)
+			So(passing.commits, ShouldEqual, 1)
+			So(other.commits, ShouldEqual, 1)
+		})
+
+		Convey("All should require all the rules to pass", func() {
+			So(authorizeAndCommit(rule.All(violationErr, passing, other)), ShouldBeNil)
+			So(passing.commits+other.commits, ShouldEqual, 2)
+
+			So(authorizeAndCommit(rule.All(violationErr, passing, failing, other)), ShouldResemble, violationErr)
+			So(other.calls, ShouldEqual, 1)
+		})
+		Convey("All should return the inner error without a violation", func() {
+			So(authorizeAndCommit(rule.All(nil, passing, failing)), ShouldResemble, innerErr)
+		})
+
+		Convey("Any should require only one rule to pass", func() {
+			So(authorizeAndCommit(rule.Any(violationErr, failing, passing, other)), ShouldBeNil)
+			So(passing.commits, ShouldEqual, 1)
+			So(failing.commits, ShouldEqual, 0)
+			So(other.calls, ShouldEqual, 0)
+		})
+		Convey("Any should fail if all the rules fail", func() {
+			So(authorizeAndCommit(rule.Any(violationErr, failing, failing)), ShouldResemble, violationErr)
+			So(authorizeAndCommit(rule.Any(nil, failing, failing)), ShouldResemble, util.AggregateError{Errors: []error{innerErr, innerErr}})
+		})
+
+		Convey("Not should invert the rule without committing it", func() {
+			So(authorizeAndCommit(rule.Not(violationErr, failing)), ShouldBeNil)
+			So(failing.commits, ShouldEqual, 0)
+			So(authorizeAndCommit(rule.Not(violationErr, passing)), ShouldResemble, violationErr)
+			So(passing.commits, ShouldEqual, 0)
+		})
+
+		Convey("When should apply the rule only if the predicate holds", func() {
+			isBig := func(_ model.Account, transaction model.Transaction) bool { return transaction.Amount > 1000 }
+			So(authorizeAndCommit(rule.When(isBig, failing)), ShouldBeNil)
+			So(failing.calls, ShouldEqual, 0)
+
+			isSketchy := func(_ model.Account, transaction model.Transaction) bool { return transaction.Merchant == "Sketchy" }
+			So(authorizeAndCommit(rule.When(isSketchy, failing)), ShouldResemble, innerErr)
+			So(authorizeAndCommit(rule.When(isSketchy, passing)), ShouldBeNil)
+			So(passing.commits, ShouldEqual, 1)
+		})
+
+		Convey("Fatal errors should never be inverted or replaced", func() {
+			So(authorizeAndCommit(rule.All(violationErr, fatal)), ShouldEqual, fatalErr)
+			So(authorizeAndCommit(rule.Any(violationErr, fatal, passing)), ShouldEqual, fatalErr)
+			So(authorizeAndCommit(rule.Not(violationErr, fatal)), ShouldEqual, fatalErr)
+			So(authorizeAndCommit(rule.Not(violationErr, rule.List{failing, fatal})), ShouldResemble, util.AggregateError{Errors: []error{innerErr, fatalErr}})
+		})
+
+		Convey("They should be composable", func() {
+			authzer := rule.Any(violationErr, rule.All(nil, passing, failing), rule.Not(violationErr, failing))
+			So(authorizeAndCommit(authzer), ShouldBeNil)
+			So(passing.commits, ShouldEqual, 0)
+		})
+
+		Convey("All and Any should keep the decline actions of the failing rules", func() {
+			blocks := 0
+			block := func(_ *model.Account) { blocks++ }
+			acting := newFakeRule(rule.ActionError{Err: innerErr, Action: block})
+
+			for _, authzer := range []rule.Authorizer{rule.All(violationErr, passing, acting), rule.Any(violationErr, failing, acting)} {
+				err := authorizeAndCommit(authzer)
+				So(errors.Is(err, violationErr), ShouldBeTrue)
+				for _, action := range rule.DeclineActions(err) {
+					action(&model.Account{})
+				}
+			}
+			So(blocks, ShouldEqual, 2)
+			So(authorizeAndCommit(rule.All(violationErr, failing)), ShouldResemble, violationErr)
+		})
+
+	})
+}
+
+func TestShadow(t *testing.T) {
+	Convey("Given a rule in shadow mode", t, func() {
+		innerErr := violation.NewError("inner-violation", "Inner violation")
+		fatalErr := errors.New("Fatal error")
+
+		inner := newFakeRule(nil)
+		shadow := rule.NewShadow("inner", inner)
+
+		Convey("It should allow transactions the rule allows", func() {
+			So(authorizeAndCommit(shadow), ShouldBeNil)
+			So(inner.commits, ShouldEqual, 1)
+		})
+
+		Convey("It should return the rule violations as shadow errors", func() {
+			inner.err = innerErr
+			commit, err := shadow.Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, rule.ShadowError{Err: innerErr})
+			So(errors.Is(err, innerErr), ShouldBeTrue)
+
+			Convey("And still commit the rule as if it had approved", func() {
+				commit(&model.Account{})
+				So(inner.commits, ShouldEqual, 1)
+			})
+		})
+
+		Convey("It should NOT allow the rule to change the account on commit", func() {
+			inner := rule.AuthorizerFunc(func(_ model.Account, _ model.Transaction) (rule.CommitFunc, error) {
+				return func(account *model.Account) { account.AvailableLimit-- }, nil
+			})
+			commit, _ := rule.NewShadow("fee", inner).Authorize(dummyAccount, dummyTransaction)
+			account := dummyAccount
+			commit(&account)
+			So(account, ShouldResemble, dummyAccount)
+		})
+
+		Convey("Its violations should NOT fail the combinators", func() {
+			inner.err = innerErr
+			shadowErr := rule.ShadowError{Err: innerErr}
+			passing, failing := newFakeRule(nil), newFakeRule(violation.NewError("other-violation", "Other violation"))
+
+			commit, err := rule.FirstFailure{shadow, passing}.Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, shadowErr)
+			commit(&model.Account{})
+			So(inner.commits+passing.commits, ShouldEqual, 2)
+
+			_, err = rule.FirstFailure{shadow, failing}.Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, util.AggregateError{Errors: []error{shadowErr, failing.err}})
+
+			violationErr := violation.NewError("custom-violation", "Custom violation")
+			_, err = rule.All(violationErr, shadow, passing).Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, shadowErr)
+			So(passing.calls, ShouldEqual, 2)
+			_, err = rule.All(violationErr, shadow, failing).Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, util.AggregateError{Errors: []error{shadowErr, violationErr}})
+
+			_, err = rule.Any(violationErr, shadow, failing).Authorize(dummyAccount, dummyTransaction)
+			So(err, ShouldResemble, shadowErr)
+			So(failing.calls, ShouldEqual, 2)
+			So(authorizeAndCommit(rule.Not(violationErr, shadow)), ShouldResemble, violationErr)
+		})
+
+		Convey("It should propagate fatal errors", func() {
+			inner.err = fatalErr
+			So(authorizeAndCommit(shadow), ShouldEqual, fatalErr)
+		})
+
+		Convey("It should count the outcomes in its metrics", func() {
+			authorizeAndCommit(shadow)
+			inner.err = innerErr
+			authorizeAndCommit(shadow)
+			authorizeAndCommit(shadow)
+
+			expected := rule.Metrics{Rule: "inner", Evaluated: 3, Declined: 2, Violations: map[violation.Code]int{"inner-violation": 2}}
+			So(shadow.RuleMetrics(), ShouldResemble, []rule.Metrics{expected})
+			So(rule.CollectMetrics(rule.List{inner, rule.FirstFailure{shadow}}), ShouldResemble, []rule.Metrics{expected})
+		})
+
+		Convey("Its errors should be split from the actual ones", func() {
+			shadowErr := rule.ShadowError{Err: innerErr}
+
+			declined, shadowed := rule.SplitShadow(util.AggregateError{Errors: []error{innerErr, shadowErr, fatalErr}})
+			So(declined, ShouldResemble, util.AggregateError{Errors: []error{innerErr, fatalErr}})
+			So(shadowed, ShouldResemble, shadowErr)
+
+			declined, shadowed = rule.SplitShadow(nil)
+			So(declined, ShouldBeNil)
+			So(shadowed, ShouldBeNil)
+		})
+	})
+}
+
+func TestRollout(t *testing.T) {
+	Convey("Given a rule rollout", t, func() {
+		treatment, control := newFakeRule(violation.NewError("treatment-violation", "Treatment")), newFakeRule(nil)
+		accountIDs := make([]string, 1000)
+		for i := range accountIDs {
+			accountIDs[i] = fmt.Sprintf("account-%d", i)
+		}
+		countTreatment := func(rollout *rule.Rollout) int {
+			count := 0
+			for _, id := range accountIDs {
+				if rollout.Cohort(id) == rule.CohortTreatment {
+					count++
+				}
+			}
+			return count
+		}
+
+		Convey("It should put the configured percentage of accounts in the treatment cohort", func() {
+			So(countTreatment(rule.NewRollout("ramp", 0, treatment, control)), ShouldEqual, 0)
+			So(countTreatment(rule.NewRollout("ramp", 100, treatment, control)), ShouldEqual, len(accountIDs))
+			So(countTreatment(rule.NewRollout("ramp", 20, treatment, control)), ShouldBeBetween, 150, 250)
+		})
+
+		Convey("It should choose the cohorts deterministically", func() {
+			rollout, again := rule.NewRollout("ramp", 20, treatment, control), rule.NewRollout("ramp", 20, nil, nil)
+			larger := rule.NewRollout("ramp", 50, treatment, control)
+			for _, id := range accountIDs {
+				So(rollout.Cohort(id), ShouldEqual, again.Cohort(id))
+				if rollout.Cohort(id) == rule.CohortTreatment {
+					So(larger.Cohort(id), ShouldEqual, rule.CohortTreatment)
+				}
+			}
+		})
+
+		Convey("It should apply the rule of the account cohort and count its outcomes", func() {
+			rollout := rule.NewRollout("ramp", 20, treatment, control)
+			for _, id := range accountIDs[:100] {
+				account := dummyAccount
+				account.ID = id
+				_, err := rollout.Authorize(account, dummyTransaction)
+				if rollout.Cohort(id) == rule.CohortTreatment {
+					So(err, ShouldNotBeNil)
+				} else {
+					So(err, ShouldBeNil)
+				}
+			}
+
+			metrics := rollout.RuleMetrics()
+			So(metrics, ShouldHaveLength, 2)
+			So(metrics[0].Cohort, ShouldEqual, rule.CohortTreatment)
+			So(metrics[0].Evaluated, ShouldEqual, treatment.calls)
+			So(metrics[0].DeclineRate(), ShouldEqual, 1)
+			So(metrics[1].Cohort, ShouldEqual, rule.CohortControl)
+			So(metrics[1].Evaluated, ShouldEqual, control.calls)
+			So(metrics[1].DeclineRate(), ShouldEqual, 0)
+			So(treatment.calls+control.calls, ShouldEqual, 100)
+		})
+
+		Convey("It should allow the control cohort without a control rule", func() {
+			rollout := rule.NewRollout("ramp", 0, treatment, nil)
+			So(authorizeAndCommit(rollout), ShouldBeNil)
+			So(rollout.RuleMetrics()[1].Evaluated, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestAssessments(t *testing.T) {
+	Convey("Given some rules assessing the transactions", t, func() {
+		assessment := &model.RiskAssessment{Score: 10}
+		assessing, other := &fakeAssessor{assessments: map[string]*model.RiskAssessment{"acc": assessment}}, newFakeRule(nil)
+
+		Convey("It should take the assessment of the account", func() {
+			So(rule.TakeAssessment("acc", other, assessing), ShouldEqual, assessment)
+
+			Convey("Only once", func() {
+				So(rule.TakeAssessment("acc", assessing), ShouldBeNil)
+			})
+		})
+		Convey("It should NOT take the assessments of other accounts", func() {
+			So(rule.TakeAssessment("other", assessing), ShouldBeNil)
+			So(rule.TakeAssessment("acc", assessing), ShouldEqual, assessment)
+		})
+		Convey("It should take the assessments of composed rules", func() {
+			always := func(_ model.Account, _ model.Transaction) bool { return true }
+			authzer := rule.List{other, rule.FirstFailure{rule.When(always, rule.Named{Name: "risk", Authorizer: assessing})}}
+			So(rule.TakeAssessment("acc", authzer), ShouldEqual, assessment)
+		})
+		Convey("It should NOT take the assessments of rules in shadow mode", func() {
+			So(rule.TakeAssessment("acc", rule.NewShadow("risk", assessing)), ShouldBeNil)
+		})
+	})
+}
+
+func TestTrace(t *testing.T) {
+	Convey("Given some authorizers being traced", t, func() {
+		innerErr := violation.NewError("inner-violation", "Inner violation")
+		passing, failing := newFakeRule(nil), newFakeRule(innerErr)
+		trace := &rule.Trace{}
+
+		outcomes := func() []string {
+			results := make([]string, len(trace.Decisions))
+			for i, decision := range trace.Decisions {
+				results[i] = fmt.Sprintf("%s:%s", decision.Rule, decision.Outcome)
+			}
+			return results
+		}
+
+		Convey("It should record the decision of each rule in a list", func() {
+			never := func(_ model.Account, _ model.Transaction) bool { return false }
+			list := rule.List{
+				rule.Named{Name: "first", Authorizer: passing},
+				rule.Named{Name: "second", Authorizer: failing},
+				rule.FirstFailure{rule.Named{Name: "third", Authorizer: failing}, rule.Named{Name: "fourth", Authorizer: passing}},
+				rule.When(never, rule.Named{Name: "fifth", Authorizer: passing}),
+			}
+			_, err := rule.AuthorizeTraced(list, dummyAccount, dummyTransaction, trace)
+			So(err, ShouldNotBeNil)
+			So(outcomes(), ShouldResemble, []string{"first:pass", "second:fail", "third:fail", "fourth:skip", "fifth:skip"})
+			So(passing.calls, ShouldEqual, 1)
+
+			decision := trace.Decisions[1]
+			So(decision.Violations, ShouldResemble, []violation.Code{"inner-violation"})
+			So(decision.Message, ShouldEqual, "Inner violation")
+		})
+
+		Convey("It should record shadow violations as passing", func() {
+			rule.AuthorizeTraced(rule.NewShadow("shadow", failing), dummyAccount, dummyTransaction, trace)
+			So(outcomes(), ShouldResemble, []string{"shadow:pass"})
+			So(trace.Decisions[0].Violations, ShouldResemble, []violation.Code{"inner-violation"})
+		})
+
+		Convey("It should name the rules by their types or functions", func() {
+			So(rule.NameOf(passing), ShouldEqual, "rule_test.fakeRule")
+			So(rule.NameOf(rule.AuthorizerFunc(authorizeNothing)), ShouldEqual, "rule_test.authorizeNothing")
+			So(rule.NameOf(rule.NewRollout("ramp", 10, passing, nil)), ShouldEqual, "ramp")
+		})
+	})
+}
+
+func authorizeNothing(_ model.Account, _ model.Transaction) (rule.CommitFunc, error) {
+	return nil, nil
+}
+
+// fakeRule is an authorizer returning a fixed error and counting how many
+// times it was called and committed.
+type fakeRule struct {
+	err            error
+	calls, commits int
+}
+
+func newFakeRule(err error) *fakeRule {
+	return &fakeRule{err: err}
+}
+
+func (f *fakeRule) Authorize(_ model.Account, _ model.Transaction) (rule.CommitFunc, error) {
+	f.calls++
+	return func(_ *model.Account) { f.commits++ }, f.err
+}
+
+type fakeAssessor struct {
+	fakeRule
+	assessments map[string]*model.RiskAssessment
+}
+
+func (f *fakeAssessor) TakeAssessment(accountID string) *model.RiskAssessment {
+	assessment := f.assessments[accountID]
+	delete(f.assessments, accountID)
+	return assessment
+}
+
+func authorizeAndCommit(authzer rule.Authorizer) error {
+	commit, err := authzer.Authorize(dummyAccount, dummyTransaction)
+	if err == nil && commit != nil {
+		commit(&model.Account{})
+	}
+	return err
+}
+
 func configureMocks(mocks []*mock_rule.MockAuthorizer, skipIndexes ...int) {
 	for i, authzer := range mocks {
 		if containsInt(skipIndexes, i) {
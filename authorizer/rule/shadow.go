@@ -0,0 +1,123 @@
+package rule
+
+import (
+	"errors"
+	"nuledger/model"
+	"nuledger/util"
+)
+
+// ShadowError is the error returned by a Shadow authorizer wrapping the
+// violations of its inner rule, which must not prevent the transaction from
+// being executed but only be reported.
+type ShadowError struct {
+	Err error
+}
+
+// Error implements the error interface, returning the wrapped error message.
+func (e ShadowError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error, so that it can be inspected with the
+// standard errors package functions.
+func (e ShadowError) Unwrap() error {
+	return e.Err
+}
+
+// SplitShadow separates the errors represented by the given error, including
+// the ones inside an util.AggregateError, into the ones that actually decline
+// the transaction and the ShadowErrors that must only be reported. Either of
+// them is nil if there are no such errors.
+func SplitShadow(err error) (declined error, shadow error) {
+	errs := []error{err}
+	var aggErr util.AggregateError
+	if errors.As(err, &aggErr) {
+		errs = aggErr.Errors
+	}
+
+	var declinedErrs, shadowErrs []error
+	for _, innerErr := range errs {
+		if innerErr == nil {
+			continue
+		}
+		if errors.As(innerErr, &ShadowError{}) {
+			shadowErrs = append(shadowErrs, innerErr)
+		} else {
+			declinedErrs = append(declinedErrs, innerErr)
+		}
+	}
+	return util.AggregateErrors(declinedErrs), util.AggregateErrors(shadowErrs)
+}
+
+// flatten returns the errors represented by the given error, which are the ones
+// inside it if it is an util.AggregateError, or none if it is nil.
+func flatten(err error) []error {
+	var aggErr util.AggregateError
+	if errors.As(err, &aggErr) {
+		return aggErr.Errors
+	}
+	if err == nil {
+		return nil
+	}
+	return []error{err}
+}
+
+// Shadow is an authorizer running some rule under evaluation in shadow mode:
+// the violations of the rule never decline the transactions, being returned as
+// ShadowErrors instead and counted in the metrics of the rule.
+//
+// The rule still has its commit function called when the transactions are
+// executed, as if it had approved them, so that its state evolves as it would
+// if it were enabled. The commit function is called with a copy of the account
+// though, so the rule can't change the actual account state (e.g. charge fees).
+//
+// The ShadowErrors don't fail the rule in any of the combinators either, which
+// keep evaluating the other rules and return the ShadowErrors together with
+// their result. Fatal errors (i.e. not violations) of the rule are propagated
+// as they are.
+type Shadow struct {
+	authzer Authorizer
+	metrics Metrics
+}
+
+// Ensure Shadow implements the Authorizer and MetricsReporter interfaces
+var (
+	_ Authorizer      = &Shadow{}
+	_ MetricsReporter = &Shadow{}
+)
+
+// NewShadow creates a Shadow authorizer for the given rule, identified by the
+// name in its metrics.
+func NewShadow(name string, authzer Authorizer) *Shadow {
+	return &Shadow{authzer: authzer, metrics: Metrics{Rule: name}}
+}
+
+// Authorize implements the Authorizer interface.
+func (s *Shadow) Authorize(account model.Account, transaction model.Transaction) (CommitFunc, error) {
+	commit, err := s.authzer.Authorize(account, transaction)
+	if err != nil && !isViolation(err) {
+		return nil, err
+	}
+	s.metrics.record(err)
+
+	var shadowCommit CommitFunc
+	if commit != nil {
+		shadowCommit = func(account *model.Account) { commit(account.Copy()) }
+	}
+	if err != nil {
+		return shadowCommit, ShadowError{Err: err}
+	}
+	return shadowCommit, nil
+}
+
+// RuleName implements the RuleNamer interface, returning the name of the rule
+// in shadow mode.
+func (s *Shadow) RuleName() string {
+	return s.metrics.Rule
+}
+
+// RuleMetrics implements the MetricsReporter interface, returning the metrics
+// of the rule in shadow mode.
+func (s *Shadow) RuleMetrics() []Metrics {
+	return []Metrics{s.metrics.copy()}
+}
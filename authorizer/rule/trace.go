@@ -0,0 +1,98 @@
+package rule
+
+import (
+	"nuledger/model"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Trace records the decision of each rule when authorizing a transaction, for
+// explaining the final decision.
+type Trace struct {
+	Decisions []model.RuleDecision
+}
+
+// Tracer is implemented by the authorizers composed of other rules, so that
+// the decision of each of those rules can be recorded separately in a Trace.
+type Tracer interface {
+	AuthorizeTraced(account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error)
+}
+
+// AuthorizeTraced calls the given authorizer recording its decisions in the
+// trace. Tracer authorizers record the decision of each of their rules, while
+// any other authorizer is recorded as a single rule named by NameOf.
+//
+// A rule returning only ShadowErrors is recorded as passing, since it doesn't
+// decline the transaction, but still with its violations.
+func AuthorizeTraced(authzer Authorizer, account model.Account, transaction model.Transaction, trace *Trace) (CommitFunc, error) {
+	if tracer, ok := authzer.(Tracer); ok {
+		return tracer.AuthorizeTraced(account, transaction, trace)
+	}
+
+	start := time.Now()
+	commit, err := authzer.Authorize(account, transaction)
+	decision := model.RuleDecision{Rule: NameOf(authzer), Outcome: model.RuleOutcomePass, Duration: time.Since(start)}
+	if err != nil {
+		if declined, _ := SplitShadow(err); declined != nil {
+			decision.Outcome = model.RuleOutcomeFail
+		}
+		decision.Violations = violationCodes(err)
+		decision.Message = err.Error()
+	}
+	trace.Decisions = append(trace.Decisions, decision)
+	return commit, err
+}
+
+// skip records the given authorizers as skipped in the trace, including each
+// of the rules of the ones composed of other rules.
+func (t *Trace) skip(authzers ...Authorizer) {
+	for _, authzer := range authzers {
+		switch authzer := authzer.(type) {
+		case List:
+			t.skip(authzer...)
+		case FirstFailure:
+			t.skip(authzer...)
+		case *conditional:
+			t.skip(authzer.authzer)
+		default:
+			t.Decisions = append(t.Decisions, model.RuleDecision{Rule: NameOf(authzer), Outcome: model.RuleOutcomeSkip})
+		}
+	}
+}
+
+// Named is an authorizer with an explicit name, used to identify it in traces.
+type Named struct {
+	Name string
+	Authorizer
+}
+
+// RuleNamer is implemented by the authorizers that have their own name, to be
+// identified in traces.
+type RuleNamer interface {
+	RuleName() string
+}
+
+// NameOf returns the name of the given authorizer: the name of a Named or
+// RuleNamer authorizer, the name of the function of an AuthorizerFunc or the
+// name of the type of any other authorizer.
+func NameOf(authzer Authorizer) string {
+	switch authzer := authzer.(type) {
+	case Named:
+		return authzer.Name
+	case *Named:
+		return authzer.Name
+	case RuleNamer:
+		return authzer.RuleName()
+	case AuthorizerFunc:
+		name := runtime.FuncForPC(reflect.ValueOf(authzer).Pointer()).Name()
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+
+	typ := reflect.TypeOf(authzer)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.String()
+}
@@ -9,6 +9,12 @@ import (
 	"nuledger/model/violation"
 )
 
+// LimitDetails are the details of the violations of limits on the amounts of
+// the transactions, with how much could still be spent within the limit.
+type LimitDetails struct {
+	Remaining int64 `json:"remaining"`
+}
+
 // AccountCardActive is a rule.AuthorizerFunc to check if the account card is
 // active and returns a card-not-active violation error otherwise.
 func AccountCardActive(account model.Account, _ model.Transaction) (rule.CommitFunc, error) {
@@ -20,10 +26,10 @@ func AccountCardActive(account model.Account, _ model.Transaction) (rule.CommitF
 
 // SufficientLimit is a rule.AuthorizerFunc to check if the account has
 // sufficient limit for performing the given transaction and returns an
-// insufficient-limit violation error otherwise.
+// insufficient-limit violation error otherwise, with the LimitDetails.
 func SufficientLimit(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
 	if account.AvailableLimit < transaction.Amount {
-		return nil, violation.ErrorInsufficientLimit
+		return nil, violation.ErrorInsufficientLimit.WithDetails(&LimitDetails{Remaining: account.AvailableLimit})
 	}
 	return nil, nil
 }
@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"math"
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// minRelativeStdDev is the minimum standard deviation of the amounts of an
+// account relative to their mean, so that an account whose amounts barely vary
+// (or are all the same) doesn't have any slightly higher amount seen as
+// unusual.
+const minRelativeStdDev = 0.1
+
+// AmountAnomaly is a rule.Authorizer to detect transactions with amounts that
+// are unusually high for an account. It keeps streaming statistics of the
+// amounts of the executed transactions of each account, and declines the ones
+// which are more than a configurable number of standard deviations above the
+// mean amount of the account. The standard deviation is at least 10% of the
+// mean, for accounts whose amounts barely vary. Only amounts above the mean are
+// considered anomalous, since unusually low ones are no risk to the account.
+//
+// New accounts have a warm-up period, during which their first transactions
+// are always authorized to build up a history for the analysis.
+type AmountAnomaly struct {
+	maxDeviations float64
+	warmUp        int
+	profiles      map[string]*util.RunningStats
+}
+
+// NewAmountAnomaly creates a new amount anomaly authorizer, which declines
+// transactions with amounts more than `maxDeviations` standard deviations above
+// the mean of the account amounts. The first `warmUp` executed transactions of
+// each account are not analyzed.
+func NewAmountAnomaly(maxDeviations float64, warmUp int) *AmountAnomaly {
+	return &AmountAnomaly{
+		maxDeviations: maxDeviations,
+		warmUp:        warmUp,
+		profiles:      map[string]*util.RunningStats{},
+	}
+}
+
+// Authorize checks if the transaction amount is too far above the history of
+// the account, and if so the transaction is not authorized and an
+// amount-anomaly violation error is returned, with the mean and the (floored)
+// standard deviation of the account amounts in its message.
+func (a *AmountAnomaly) Authorize(_ model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	stats := a.profiles[transaction.AccountID]
+	if stats == nil {
+		stats = &util.RunningStats{}
+		a.profiles[transaction.AccountID] = stats
+	}
+
+	amount := float64(transaction.Amount)
+	if stats.Count() >= a.warmUp && isUnusualAmount(stats, amount, a.maxDeviations) {
+		return nil, violation.NewError(violation.AmountAnomaly,
+			"Transaction amount is anomalous compared to the account history: mean %.2f, standard deviation %.2f",
+			stats.Mean(), amountStdDev(stats))
+	}
+	commit := func(_ *model.Account) { stats.Add(amount) }
+	return commit, nil
+}
+
+// isUnusualAmount returns whether the amount is more than `maxDeviations`
+// standard deviations above the mean of the given amounts, as of amountStdDev.
+// Amounts below the mean are never unusual.
+func isUnusualAmount(stats *util.RunningStats, amount, maxDeviations float64) bool {
+	return amount > stats.Mean()+maxDeviations*amountStdDev(stats)
+}
+
+// amountStdDev returns the standard deviation of the given amounts, floored to
+// minRelativeStdDev of their mean.
+func amountStdDev(stats *util.RunningStats) float64 {
+	return math.Max(stats.StdDev(), minRelativeStdDev*math.Abs(stats.Mean()))
+}
@@ -0,0 +1,77 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAmountAnomaly(t *testing.T) {
+	Convey("Given AmountAnomaly authorizer", t, func() {
+		authzer := rules.NewAmountAnomaly(3, 4)
+
+		test := func(accountID string, amount int64) error {
+			transaction := model.Transaction{AccountID: accountID, Amount: amount}
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+		isAnomaly := func(err error) bool {
+			var verr violation.Error
+			return errors.As(err, &verr) && verr.Code == violation.AmountAnomaly
+		}
+
+		Convey("It should authorize any amounts during the warm-up", func() {
+			So(test("acc", 10), ShouldBeNil)
+			So(test("acc", 1000), ShouldBeNil)
+			So(test("acc", 5), ShouldBeNil)
+			So(test("acc", 20000), ShouldBeNil)
+		})
+
+		Convey("After the warm-up", func() {
+			for _, amount := range []int64{90, 100, 110, 100} {
+				So(test("acc", amount), ShouldBeNil)
+			}
+
+			Convey("It should authorize usual amounts", func() {
+				So(test("acc", 120), ShouldBeNil)
+				So(test("acc", 1), ShouldBeNil)
+			})
+			Convey("It should NOT authorize unusually high amounts", func() {
+				So(isAnomaly(test("acc", 500)), ShouldBeTrue)
+
+				Convey("Nor consider them in the account history", func() {
+					So(isAnomaly(test("acc", 500)), ShouldBeTrue)
+				})
+			})
+			Convey("It should keep a separate history for each account", func() {
+				So(test("other", 500), ShouldBeNil)
+			})
+		})
+
+		Convey("After a warm-up with always the same amount", func() {
+			for i := 0; i < 4; i++ {
+				So(test("acc", 100), ShouldBeNil)
+			}
+
+			Convey("It should authorize slightly higher amounts", func() {
+				So(test("acc", 101), ShouldBeNil)
+				So(test("acc", 125), ShouldBeNil)
+			})
+			Convey("It should NOT authorize unusually high amounts", func() {
+				err := test("acc", 131)
+				So(isAnomaly(err), ShouldBeTrue)
+				So(err.Error(), ShouldEndWith, "mean 100.00, standard deviation 10.00")
+			})
+			Convey("It should authorize any lower amounts", func() {
+				So(test("acc", 1), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// NewCalendarFrequency returns a rule.Authorizer to guarantee that at most
+// `maxTransactions` are authorized for each account within the same calendar
+// `window`, e.g. 3 transactions per calendar day. The calendar used is the one
+// from the time zone of the account, so a new window starts at the account
+// holder's local midnight.
+//
+// Its Authorize function checks if the maximum number of transactions in the
+// current window has been reached, and if so the transaction is not authorized
+// and a violation error of high-frequency-small-interval is returned.
+func NewCalendarFrequency(maxTransactions int, window util.CalendarWindow) rule.Authorizer {
+	newLimiter := func(_ *model.Transaction) Limiter {
+		return &util.CalendarRateLimiter{MaxEvents: maxTransactions, Window: window}
+	}
+	keyMapper := func(tx *model.Transaction) interface{} {
+		return tx.AccountID
+	}
+	return NewFrequencyAnalyzerFunc(newLimiter, keyMapper, violation.ErrorHighFrequencySmallInterval)
+}
+
+// NewCalendarSpendLimit returns a rule.Authorizer to guarantee that the total
+// amount spent by each account does not exceed `maxAmount` within the same
+// calendar `window` of the account's time zone, e.g. 5000 per calendar month.
+//
+// Its Authorize function checks if the amount of the transaction would exceed
+// the maximum allowed amount in the current window, and if so the transaction
+// is not authorized and a spend-limit-exceeded violation error is returned.
+func NewCalendarSpendLimit(maxAmount int64, window util.CalendarWindow) rule.Authorizer {
+	newLimiter := func(_ *model.Transaction) AmountLimiter {
+		return &util.CalendarSpendLimiter{MaxAmount: maxAmount, Window: window}
+	}
+	keyMapper := func(tx *model.Transaction) interface{} {
+		return tx.AccountID
+	}
+	return NewSpendAnalyzerFunc(newLimiter, keyMapper, violation.ErrorSpendLimitExceeded)
+}
@@ -0,0 +1,75 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// calendarStartTime is 23:30 on April 5th in UTC, but 20:30 in Sao Paulo.
+var calendarStartTime = time.Date(2021, time.April, 5, 23, 30, 0, 0, time.UTC)
+
+func TestCalendarFrequency(t *testing.T) {
+	Convey("Given CalendarFrequency authorizer", t, func() {
+		authzer := rules.NewCalendarFrequency(3, util.Day)
+		account := model.Account{TimeZone: "America/Sao_Paulo"}
+
+		test := func(diff time.Duration) error {
+			commitFunc, err := authzer.Authorize(account, model.Transaction{Time: calendarStartTime.Add(diff)})
+			if commitFunc != nil {
+				commitFunc(&account)
+			}
+			return err
+		}
+
+		for i := 0; i < 3; i++ {
+			So(test(0), ShouldBeNil)
+		}
+
+		Convey("It should NOT authorize more until the account's midnight", func() {
+			So(test(3*time.Hour+29*time.Minute), shouldBeViolation, violation.ErrorHighFrequencySmallInterval)
+		})
+		Convey("It should authorize again after the account's midnight", func() {
+			So(test(3*time.Hour+30*time.Minute), ShouldBeNil)
+		})
+		Convey("It should return a fatal error for an invalid time zone", func() {
+			account.TimeZone = "Nowhere/Atlantis"
+			err := test(0)
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotHaveSameTypeAs, violation.Error{})
+		})
+	})
+}
+
+func TestCalendarSpendLimit(t *testing.T) {
+	Convey("Given CalendarSpendLimit authorizer", t, func() {
+		authzer := rules.NewCalendarSpendLimit(200, util.Week)
+		account := model.Account{TimeZone: "America/Sao_Paulo"}
+
+		test := func(diff time.Duration, amount int64) error {
+			transaction := model.Transaction{Amount: amount, Time: calendarStartTime.Add(diff)}
+			commitFunc, err := authzer.Authorize(account, transaction)
+			if commitFunc != nil {
+				commitFunc(&account)
+			}
+			return err
+		}
+
+		So(test(0, 150), ShouldBeNil)
+
+		Convey("It should NOT authorize exceeding the limit within the week", func() {
+			sundayNight := 6*24*time.Hour + 3*time.Hour
+			So(test(sundayNight, 51), shouldBeViolation, violation.ErrorSpendLimitExceeded)
+			So(test(sundayNight, 50), ShouldBeNil)
+		})
+		Convey("It should authorize the whole limit in the next week", func() {
+			nextMonday := 6*24*time.Hour + 3*time.Hour + 30*time.Minute
+			So(test(nextMonday, 200), ShouldBeNil)
+		})
+	})
+}
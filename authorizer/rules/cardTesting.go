@@ -0,0 +1,96 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+	"time"
+)
+
+// smallTransaction is the merchant and time of a small transaction attempted
+// in some account.
+type smallTransaction struct {
+	merchant string
+	time     time.Time
+}
+
+// CardTesting is a rule.Authorizer to detect card-testing attacks, where
+// fraudsters probe a stolen card with many small transactions in different
+// merchants before using it for larger purchases. It keeps the recent small
+// transactions of each account, and when they happen in too many distinct
+// merchants within the window the transaction is declined and the card of the
+// account is automatically blocked.
+//
+// The small transactions are recorded when they are executed, or when they
+// are declined by this rule itself, since declined probes are also part of the
+// attack. Small transactions declined by other rules are not recorded.
+type CardTesting struct {
+	maxAmount    int64
+	maxMerchants int
+	window       time.Duration
+	recent       map[string][]smallTransaction
+}
+
+// NewCardTesting creates a new card-testing authorizer, which considers the
+// transactions with an amount of at most `maxAmount` as small ones, and detects
+// an attack when the small transactions of an account happen in more than
+// `maxMerchants` distinct merchants within the `window`.
+func NewCardTesting(maxAmount int64, maxMerchants int, window time.Duration) *CardTesting {
+	return &CardTesting{
+		maxAmount:    maxAmount,
+		maxMerchants: maxMerchants,
+		window:       window,
+		recent:       map[string][]smallTransaction{},
+	}
+}
+
+// Authorize checks the number of distinct merchants of the recent small
+// transactions of the account, including the given one if it is small. If it
+// exceeds the maximum, the transaction is not authorized and a
+// rule.ActionError is returned with a card-testing-suspected violation and an
+// action to record the transaction and block the card of the account.
+// Otherwise the small transaction is recorded on commit.
+func (c *CardTesting) Authorize(_ model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if transaction.Amount > c.maxAmount {
+		return nil, nil
+	}
+
+	recent := c.recentSince(transaction.AccountID, transaction.Time.Add(-c.window))
+	recent = append(recent, smallTransaction{strings.ToLower(transaction.Merchant), transaction.Time})
+	record := func(_ *model.Account) { c.recent[transaction.AccountID] = recent }
+
+	if countMerchants(recent) > c.maxMerchants {
+		action := func(account *model.Account) {
+			record(account)
+			blockCard(account)
+		}
+		return nil, rule.ActionError{Err: violation.ErrorCardTestingSuspected, Action: action}
+	}
+	return record, nil
+}
+
+// recentSince returns a new slice with the small transactions of the account
+// that happened after the given time, so that the older ones can be discarded
+// without keeping them referenced.
+func (c *CardTesting) recentSince(accountID string, since time.Time) []smallTransaction {
+	var recent []smallTransaction
+	for _, small := range c.recent[accountID] {
+		if small.time.After(since) {
+			recent = append(recent, small)
+		}
+	}
+	return recent
+}
+
+func countMerchants(transactions []smallTransaction) int {
+	merchants := map[string]bool{}
+	for _, transaction := range transactions {
+		merchants[transaction.merchant] = true
+	}
+	return len(merchants)
+}
+
+func blockCard(account *model.Account) {
+	account.ActiveCard = false
+}
@@ -0,0 +1,82 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rule"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var cardTestingStartTime = time.Date(2021, time.April, 10, 3, 0, 0, 0, time.UTC)
+
+func TestCardTesting(t *testing.T) {
+	Convey("Given CardTesting authorizer", t, func() {
+		window := 10 * time.Minute
+		authzer := rules.NewCardTesting(2, 3, window)
+
+		authorize := func(accountID, merchant string, amount int64, diff time.Duration) (rule.CommitFunc, error) {
+			transaction := model.Transaction{AccountID: accountID, Merchant: merchant, Amount: amount, Time: cardTestingStartTime.Add(diff)}
+			return authzer.Authorize(model.Account{}, transaction)
+		}
+		test := func(accountID, merchant string, amount int64, diff time.Duration) error {
+			commitFunc, err := authorize(accountID, merchant, amount, diff)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			for _, action := range rule.DeclineActions(err) {
+				action(&model.Account{})
+			}
+			return err
+		}
+
+		So(test("acc", "Shop A", 1, 0), ShouldBeNil)
+		So(test("acc", "Shop B", 2, 1*time.Minute), ShouldBeNil)
+		So(test("acc", "Shop C", 1, 2*time.Minute), ShouldBeNil)
+
+		Convey("It should authorize more small transactions in the same merchants", func() {
+			So(test("acc", "shop a", 1, 3*time.Minute), ShouldBeNil)
+			So(test("acc", "Shop B", 1, 3*time.Minute), ShouldBeNil)
+		})
+		Convey("It should authorize larger transactions in other merchants", func() {
+			So(test("acc", "Shop D", 3, 3*time.Minute), ShouldBeNil)
+		})
+		Convey("It should authorize small transactions in other accounts", func() {
+			So(test("other", "Shop D", 1, 3*time.Minute), ShouldBeNil)
+		})
+		Convey("It should authorize small transactions after the window", func() {
+			So(test("acc", "Shop D", 1, window), ShouldBeNil)
+		})
+
+		Convey("It should not record small transactions that are not executed", func() {
+			commitFunc, err := authorize("acc", "Shop D", 1, 3*time.Minute)
+			So(commitFunc, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+
+			So(test("acc", "Shop A", 1, 4*time.Minute), ShouldBeNil)
+		})
+		Convey("It should record small transactions declined by it", func() {
+			So(test("acc", "Shop D", 1, 3*time.Minute), ShouldNotBeNil)
+			So(test("acc", "Shop A", 1, 4*time.Minute), ShouldNotBeNil)
+		})
+
+		Convey("It should detect small transactions in too many merchants", func() {
+			commitFunc, err := authorize("acc", "Shop D", 1, 3*time.Minute)
+			So(commitFunc, ShouldBeNil)
+			So(errors.Is(err, violation.ErrorCardTestingSuspected), ShouldBeTrue)
+
+			Convey("And block the card of the account", func() {
+				var actionErr rule.ActionError
+				So(errors.As(err, &actionErr), ShouldBeTrue)
+
+				account := model.Account{ActiveCard: true}
+				actionErr.Action(&account)
+				So(account.ActiveCard, ShouldBeFalse)
+			})
+		})
+	})
+}
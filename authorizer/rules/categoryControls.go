@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// categoryKey is the key of the spending limiters of each account category.
+type categoryKey struct {
+	AccountID string
+	Category  model.Category
+	Window    util.CalendarWindow
+}
+
+// CategoryControls is a rule.Authorizer to enforce the controls configured in
+// each account for the merchant categories of the transactions, e.g. blocking
+// any gambling transactions or spending at most 200 per day on fuel. The
+// controls are read from the account on every authorization, so they can be
+// changed at any time while the amounts spent are kept by the authorizer.
+type CategoryControls struct {
+	limiters map[categoryKey]*util.CalendarSpendLimiter
+}
+
+// NewCategoryControls creates a new CategoryControls authorizer.
+func NewCategoryControls() *CategoryControls {
+	return &CategoryControls{limiters: map[categoryKey]*util.CalendarSpendLimiter{}}
+}
+
+// Authorize checks the controls of the account for the category of the given
+// transaction. It returns a category-blocked violation error if the category is
+// blocked in the account, or a category-limit-exceeded violation error if the
+// transaction would exceed the maximum amount for the category in the current
+// calendar window of the account's time zone.
+func (c *CategoryControls) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	category := transaction.Category()
+	control, ok := account.CategoryControls[category]
+	if !ok {
+		return nil, nil
+	}
+	if control.Blocked {
+		return nil, violation.ErrorCategoryBlocked
+	}
+	if control.MaxAmount <= 0 {
+		return nil, nil
+	}
+
+	event, err := localTime(account, transaction)
+	if err != nil {
+		return nil, err
+	}
+	limiter := c.getLimiter(categoryKey{transaction.AccountID, category, control.Window})
+	limiter.MaxAmount = control.MaxAmount
+	if !limiter.Allows(event, transaction.Amount) {
+		return nil, violation.ErrorCategoryLimitExceeded.WithDetails(&LimitDetails{Remaining: limiter.Remaining(event)})
+	}
+	commit := func(_ *model.Account) { limiter.Take(event, transaction.Amount) }
+	return commit, nil
+}
+
+// getLimiter tries to get the existing spend limiter for the given key and
+// creates a new one if there is none yet.
+func (c *CategoryControls) getLimiter(key categoryKey) *util.CalendarSpendLimiter {
+	limiter := c.limiters[key]
+	if limiter != nil {
+		return limiter
+	}
+
+	limiter = &util.CalendarSpendLimiter{Window: key.Window}
+	c.limiters[key] = limiter
+	return limiter
+}
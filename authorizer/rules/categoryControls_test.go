@@ -0,0 +1,58 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var categoryStartTime = time.Date(2021, time.April, 6, 10, 0, 0, 0, time.UTC)
+
+func TestCategoryControls(t *testing.T) {
+	Convey("Given CategoryControls authorizer", t, func() {
+		authzer := rules.NewCategoryControls()
+		account := model.Account{CategoryControls: map[model.Category]model.CategoryControl{
+			model.CategoryGambling: {Blocked: true},
+			model.CategoryFuel:     {MaxAmount: 200, Window: util.Day},
+		}}
+
+		test := func(mcc string, amount int64, diff time.Duration) error {
+			transaction := model.Transaction{MCC: mcc, Amount: amount, Time: categoryStartTime.Add(diff)}
+			commitFunc, err := authzer.Authorize(account, transaction)
+			if commitFunc != nil {
+				commitFunc(&account)
+			}
+			return err
+		}
+
+		Convey("It should authorize categories without controls", func() {
+			So(test("5812", 1000, 0), ShouldBeNil)
+			So(test("", 1000, 0), ShouldBeNil)
+		})
+
+		Convey("It should NOT authorize blocked categories", func() {
+			So(test("7995", 1, 0), ShouldResemble, violation.ErrorCategoryBlocked)
+		})
+
+		Convey("When spending on a limited category", func() {
+			So(test("5542", 150, 0), ShouldBeNil)
+
+			Convey("It should NOT authorize exceeding the limit in the same day", func() {
+				So(test("5541", 51, 1*time.Hour), shouldBeViolation, violation.ErrorCategoryLimitExceeded)
+				So(test("5541", 50, 1*time.Hour), ShouldBeNil)
+			})
+			Convey("It should authorize the whole limit in the next day", func() {
+				So(test("5542", 200, 14*time.Hour), ShouldBeNil)
+			})
+			Convey("It should use the latest limit configured in the account", func() {
+				account.CategoryControls[model.CategoryFuel] = model.CategoryControl{MaxAmount: 500}
+				So(test("5542", 350, 1*time.Hour), ShouldBeNil)
+			})
+		})
+	})
+}
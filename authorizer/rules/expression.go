@@ -0,0 +1,154 @@
+package rules
+
+import (
+	"fmt"
+	"nuledger/authorizer/expr"
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+	"time"
+)
+
+// ExpressionVars are the variables available to the expressions of the
+// Expression authorizer, with their types. The time-based ones are in the
+// account time zone.
+var ExpressionVars = map[string]expr.Type{
+	"amount":                 expr.TypeNumber,
+	"merchant":               expr.TypeString,
+	"mcc":                    expr.TypeString,
+	"category":               expr.TypeString,
+	"country":                expr.TypeString,
+	"recurring":              expr.TypeBool,
+	"hour":                   expr.TypeNumber,
+	"weekday":                expr.TypeString,
+	"account.activeCard":     expr.TypeBool,
+	"account.availableLimit": expr.TypeNumber,
+	"account.overdraftLimit": expr.TypeNumber,
+	"account.homeCountry":    expr.TypeString,
+}
+
+// Expression is a rule.Authorizer that declines the transactions matching an
+// expression of the expr language, so that custom rules can be written without
+// changing the code, e.g. `amount > 500 && merchant startsWith "CASINO"`.
+//
+// The expressions can use the ExpressionVars of the transaction and account,
+// and the count(window) and sum(window) aggregates, which are the number and
+// the total amount of the executed transactions of the account within the
+// window before the transaction.
+type Expression struct {
+	expression *expr.Expression
+	violation  violation.Error
+	history    map[string][]pastTransaction
+}
+
+// NewExpression creates a new expression authorizer, which returns the given
+// `violation` error for the transactions matching the `source` expression. It
+// returns an error if the expression is not valid.
+func NewExpression(source string, violation violation.Error) (*Expression, error) {
+	expression, err := expr.Compile(source, ExpressionVars)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{
+		expression: expression,
+		violation:  violation,
+		history:    map[string][]pastTransaction{},
+	}, nil
+}
+
+// Authorize evaluates the expression for the transaction, and if it matches
+// the transaction is not authorized and the configured violation error is
+// returned.
+func (e *Expression) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	local, err := localTime(account, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	history := e.history[transaction.AccountID]
+	since := transaction.Time.Add(-e.expression.MaxWindow())
+	for len(history) > 0 && !history[0].time.After(since) {
+		history = history[1:]
+	}
+
+	env := expressionEnv{account, transaction, local, history}
+	if e.expression.Eval(env) {
+		return nil, e.violation
+	}
+	if e.expression.MaxWindow() == 0 {
+		return nil, nil
+	}
+	commit := func(_ *model.Account) {
+		// the capacity is limited so that the older transactions are copied out
+		// of the previous array, which is then no longer referenced
+		recent := history[:len(history):len(history)]
+		e.history[transaction.AccountID] = append(recent,
+			pastTransaction{transaction.ID, transaction.Amount, transaction.Time})
+	}
+	return commit, nil
+}
+
+// expressionEnv is the expr.Env for evaluating an expression for a transaction.
+type expressionEnv struct {
+	account     model.Account
+	transaction model.Transaction
+	local       time.Time
+	history     []pastTransaction
+}
+
+func (e expressionEnv) Var(name string) interface{} {
+	switch name {
+	case "amount":
+		return float64(e.transaction.Amount)
+	case "merchant":
+		return e.transaction.Merchant
+	case "mcc":
+		return e.transaction.MCC
+	case "category":
+		return string(e.transaction.Category())
+	case "country":
+		if e.transaction.Location == nil {
+			return ""
+		}
+		return e.transaction.Location.Country
+	case "recurring":
+		return e.transaction.Recurring
+	case "hour":
+		return float64(e.local.Hour())
+	case "weekday":
+		return strings.ToLower(e.local.Weekday().String())
+	case "account.activeCard":
+		return e.account.ActiveCard
+	case "account.availableLimit":
+		return float64(e.account.AvailableLimit)
+	case "account.overdraftLimit":
+		return float64(e.account.OverdraftLimit)
+	case "account.homeCountry":
+		return e.account.HomeCountry
+	default:
+		// unreachable, since the expressions are compiled with ExpressionVars
+		panic(fmt.Sprintf("Unknown expression variable %q", name))
+	}
+}
+
+func (e expressionEnv) Count(window time.Duration) float64 {
+	count := 0
+	e.forEachInWindow(window, func(_ pastTransaction) { count++ })
+	return float64(count)
+}
+
+func (e expressionEnv) Sum(window time.Duration) float64 {
+	var sum int64
+	e.forEachInWindow(window, func(past pastTransaction) { sum += past.amount })
+	return float64(sum)
+}
+
+func (e expressionEnv) forEachInWindow(window time.Duration, f func(pastTransaction)) {
+	since := e.transaction.Time.Add(-window)
+	for _, past := range e.history {
+		if past.time.After(since) {
+			f(past)
+		}
+	}
+}
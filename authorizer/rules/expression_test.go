@@ -0,0 +1,73 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/expr"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var expressionStartTime = time.Date(2021, time.April, 14, 22, 0, 0, 0, time.UTC)
+
+func TestExpression(t *testing.T) {
+	Convey("Given Expression authorizer", t, func() {
+		ruleViolation := violation.NewError("custom-rule", "Matched a custom rule")
+		account := model.Account{AvailableLimit: 1000, HomeCountry: "BR"}
+
+		newTest := func(source string) func(merchant string, amount int64, diff time.Duration) error {
+			authzer, err := rules.NewExpression(source, ruleViolation)
+			So(err, ShouldBeNil)
+			return func(merchant string, amount int64, diff time.Duration) error {
+				transaction := model.Transaction{Merchant: merchant, Amount: amount, Time: expressionStartTime.Add(diff), MCC: "7995"}
+				commitFunc, err := authzer.Authorize(account, transaction)
+				if commitFunc != nil {
+					commitFunc(&account)
+				}
+				return err
+			}
+		}
+
+		Convey("It should decline transactions matching the expression", func() {
+			test := newTest(`amount > 500 && merchant startsWith "CASINO"`)
+			So(test("Casino Royale", 600, 0), ShouldResemble, ruleViolation)
+			So(test("Casino Royale", 500, 0), ShouldBeNil)
+			So(test("Bookstore", 600, 0), ShouldBeNil)
+		})
+
+		Convey("It should evaluate every one of the expression variables", func() {
+			conditions := map[expr.Type]string{expr.TypeNumber: " >= 0", expr.TypeString: ` != "?"`, expr.TypeBool: " || true"}
+			for name, typ := range rules.ExpressionVars {
+				test := newTest(name + conditions[typ])
+				So(test("Bookstore", 10, 0), ShouldResemble, ruleViolation)
+			}
+		})
+
+		Convey("It should evaluate transaction and account variables", func() {
+			test := newTest(`category == "gambling" && hour >= 19 && weekday == "wednesday" && account.homeCountry == "BR" && amount > account.availableLimit / 2`)
+			So(test("Casino Royale", 501, 0), ShouldResemble, ruleViolation)
+			So(test("Casino Royale", 500, 0), ShouldBeNil)
+			account.TimeZone = "Asia/Tokyo"
+			So(test("Casino Royale", 501, 0), ShouldBeNil)
+		})
+
+		Convey("It should evaluate window aggregates of executed transactions", func() {
+			test := newTest(`count(10m) >= 2 || sum(1h) + amount > 100`)
+			So(test("Bakery", 10, 0), ShouldBeNil)
+			So(test("Bakery", 10, 1*time.Minute), ShouldBeNil)
+			So(test("Bakery", 10, 2*time.Minute), ShouldResemble, ruleViolation)
+			So(test("Bakery", 80, 11*time.Minute), ShouldBeNil)
+			So(test("Bakery", 1, 12*time.Minute), ShouldResemble, ruleViolation)
+			So(test("Bakery", 20, 1*time.Hour+1*time.Minute), ShouldBeNil)
+		})
+
+		Convey("It should return an error for invalid expressions", func() {
+			authzer, err := rules.NewExpression(`balance > 500`, ruleViolation)
+			So(authzer, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -5,8 +5,17 @@ import (
 	"nuledger/model"
 	"nuledger/model/violation"
 	"nuledger/util"
+	"time"
 )
 
+// Limiter is the interface of the limiters used by the FrequencyAnalyzer for
+// limiting the frequency of transactions. It is implemented by both the rolling
+// util.RateLimiter and the calendar-aligned util.CalendarRateLimiter.
+type Limiter interface {
+	Allows(event time.Time) bool
+	Take(event time.Time) bool
+}
+
 // FrequencyAnalyzer is a generic rule.Authorizer that can be used for any kind
 // of authorization rule based on the frequency of transactions given a certain
 // constraint.
@@ -19,14 +28,46 @@ import (
 // should be included in. Examples of this are analyising each account
 // transactions or each merchant's transactions separately.
 //
-// The frequency of transactions is limited via a util.RateLimiter, for which a
-// base limiter should be provided as a template for any internal rate limiters
-// that may need to be created for new transaction groups.
+// The frequency of transactions is limited via a Limiter, for which a factory
+// function is called whenever a new transaction group is created. The factory
+// can also return no limiter for a transaction, exempting it from the analysis
+// altogether. The events sent to calendar-aligned limiters are the transaction
+// times in the time zone of the account, so that they use the account's local
+// calendar.
+//
+// The recurring charges of subscriptions registered in the account are never
+// analyzed, since they are agreed upon by the account holder.
+//
+// Its violations have FrequencyDetails, with the last transaction executed in
+// the same group and when a transaction would be allowed again if the limiter
+// can tell it (i.e. if it has a RetryAfter method like util.RateLimiter).
 type FrequencyAnalyzer struct {
-	baseLimiter *util.RateLimiter
-	keyMapper   func(*model.Transaction) interface{}
-	limiters    map[interface{}]*util.RateLimiter
-	violation   violation.Error
+	newLimiter func(*model.Transaction) Limiter
+	keyMapper  func(*model.Transaction) interface{}
+	groups     map[interface{}]*frequencyGroup
+	violation  violation.Error
+}
+
+// FrequencyDetails are the details of the violations of a FrequencyAnalyzer.
+type FrequencyDetails struct {
+	// RetryAfter is the earliest time when a transaction of the same group
+	// would be allowed, if known.
+	RetryAfter *time.Time `json:"retry-after,omitempty"`
+	// LastTransaction is the most recent transaction executed in the same
+	// group, which is the duplicated one of double-transaction violations.
+	LastTransaction DuplicateMatch `json:"last-transaction"`
+}
+
+// frequencyGroup is the state of a group of transactions analyzed together.
+type frequencyGroup struct {
+	limiter Limiter
+	last    DuplicateMatch
+}
+
+// retryLimiter is implemented by the limiters that can tell when an event
+// would be allowed again.
+type retryLimiter interface {
+	RetryAfter(event time.Time) (time.Time, bool)
 }
 
 // NewFrequencyAnalyzer creates a new frequency analyzer authorizer which limits
@@ -37,37 +78,94 @@ type FrequencyAnalyzer struct {
 // each transaction. Finally, when the rate is exceeded its Authorizer function
 // returns the error provided as the last `violation` argument.
 func NewFrequencyAnalyzer(baseLimiter util.RateLimiter, keyMapper func(*model.Transaction) interface{}, violation violation.Error) *FrequencyAnalyzer {
+	newLimiter := func(_ *model.Transaction) Limiter {
+		copy := baseLimiter
+		return &copy
+	}
+	return NewFrequencyAnalyzerFunc(newLimiter, keyMapper, violation)
+}
+
+// NewFrequencyAnalyzerFunc creates a new frequency analyzer authorizer just
+// like NewFrequencyAnalyzer, but receiving a `newLimiter` factory function to
+// create the limiter of each new transaction group from its first transaction,
+// so any Limiter can be used and configured differently for each group. If the
+// factory returns nil, the transaction is exempt and always authorized.
+func NewFrequencyAnalyzerFunc(newLimiter func(*model.Transaction) Limiter, keyMapper func(*model.Transaction) interface{}, violation violation.Error) *FrequencyAnalyzer {
 	return &FrequencyAnalyzer{
-		baseLimiter: &baseLimiter,
-		keyMapper:   keyMapper,
-		limiters:    map[interface{}]*util.RateLimiter{},
-		violation:   violation,
+		newLimiter: newLimiter,
+		keyMapper:  keyMapper,
+		groups:     map[interface{}]*frequencyGroup{},
+		violation:  violation,
 	}
 }
 
 // Authorize checks if the given transaction is a exceeds the limit of its
 // corresponding transaction group, and if so the transaction is not authorized
 // and the violation error configured for this analyzer is returned.
-func (d *FrequencyAnalyzer) Authorize(_ model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
-	limiter := d.getLimiter(&transaction)
-	if !limiter.Allows(transaction.Time) {
-		return nil, d.violation
+func (d *FrequencyAnalyzer) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if isSubscriptionCharge(account, transaction) {
+		return nil, nil
+	}
+
+	group := d.getGroup(&transaction)
+	if group == nil {
+		return nil, nil
+	}
+	event, err := limiterTime(group.limiter, account, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if !group.limiter.Allows(event) {
+		details := &FrequencyDetails{LastTransaction: group.last}
+		if limiter, ok := group.limiter.(retryLimiter); ok {
+			if retryAfter, ok := limiter.RetryAfter(event); ok {
+				details.RetryAfter = &retryAfter
+			}
+		}
+		return nil, d.violation.WithDetails(details)
+	}
+	commit := func(_ *model.Account) {
+		group.limiter.Take(event)
+		group.last = DuplicateMatch{TransactionID: transaction.ID, Time: transaction.Time}
 	}
-	commit := func() { limiter.Take(transaction.Time) }
 	return commit, nil
 }
 
-// getLimiter tries to get the existing rate limiter for a given transaction and
-// creates a new one if there is none yet.
-func (d *FrequencyAnalyzer) getLimiter(transaction *model.Transaction) *util.RateLimiter {
+// getGroup tries to get the existing group for a given transaction and creates
+// a new one with its rate limiter if there is none yet. It returns nil if the
+// transaction is exempt from the analysis.
+func (d *FrequencyAnalyzer) getGroup(transaction *model.Transaction) *frequencyGroup {
 	key := d.keyMapper(transaction)
-	limiter := d.limiters[key]
-	if limiter != nil {
-		return limiter
+	if group := d.groups[key]; group != nil {
+		return group
+	}
+
+	limiter := d.newLimiter(transaction)
+	if limiter == nil {
+		return nil
+	}
+	group := &frequencyGroup{limiter: limiter}
+	d.groups[key] = group
+	return group
+}
+
+// localTime returns the time of the transaction in the time zone of the given
+// account. It returns an error if the account has an invalid time zone.
+func localTime(account model.Account, transaction model.Transaction) (time.Time, error) {
+	loc, err := account.Location()
+	if err != nil {
+		return time.Time{}, err
 	}
+	return transaction.Time.In(loc), nil
+}
 
-	copy := *d.baseLimiter
-	limiter = &copy
-	d.limiters[key] = limiter
-	return limiter
+// limiterTime returns the time of the transaction to be sent as an event to the
+// given limiter, which is only converted to the time zone of the account if the
+// limiter is an util.LocalTimeLimiter (e.g. a calendar-aligned one), since the
+// rolling ones don't depend on it.
+func limiterTime(limiter interface{}, account model.Account, transaction model.Transaction) (time.Time, error) {
+	if _, ok := limiter.(util.LocalTimeLimiter); ok {
+		return localTime(account, transaction)
+	}
+	return transaction.Time, nil
 }
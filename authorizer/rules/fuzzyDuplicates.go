@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AmountTolerance configures how different the amounts of two transactions can
+// be for them to still be considered duplicates. The amounts are considered
+// equivalent if their difference is within either of the tolerances.
+type AmountTolerance struct {
+	// Absolute is the maximum difference in units of currency.
+	Absolute int64
+	// Percentage is the maximum difference as a percentage of the amount of
+	// the earlier transaction (e.g. 1 for 1%).
+	Percentage float64
+}
+
+// DuplicateMatch is attached as the details of the double-transaction
+// violation errors returned by the FuzzyDuplicates authorizer, identifying the
+// earlier transaction that the declined one duplicates.
+type DuplicateMatch struct {
+	TransactionID string    `json:"transactionId,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+type fuzzyDuplicateKey struct {
+	AccountID string
+	Merchant  string
+}
+
+type pastTransaction struct {
+	id     string
+	amount int64
+	time   time.Time
+}
+
+// FuzzyDuplicates is a rule.Authorizer to detect double transactions like the
+// one from NewUniqueTransactions, but tolerant to small differences between
+// them. The merchant names are normalized before being compared, ignoring case,
+// spaces and punctuation (so "UBER *TRIP" is the same merchant as "Uber Trip"),
+// and the amounts are compared with a configurable tolerance. Recurring charges
+// of registered subscriptions are never considered double.
+type FuzzyDuplicates struct {
+	interval  time.Duration
+	tolerance AmountTolerance
+	recent    map[fuzzyDuplicateKey][]pastTransaction
+}
+
+// NewFuzzyDuplicates creates a new fuzzy duplicates authorizer, which considers
+// 2 transactions in the same account as double if they have equivalent
+// merchants, amounts within the `tolerance` and timestamps within the
+// `interval` of each other.
+func NewFuzzyDuplicates(interval time.Duration, tolerance AmountTolerance) *FuzzyDuplicates {
+	return &FuzzyDuplicates{
+		interval:  interval,
+		tolerance: tolerance,
+		recent:    map[fuzzyDuplicateKey][]pastTransaction{},
+	}
+}
+
+// Authorize checks if the given transaction is a double of any recent executed
+// transaction, and if so the transaction is not authorized and a
+// double-transaction violation error is returned, with a DuplicateMatch of the
+// earlier transaction as its details.
+func (f *FuzzyDuplicates) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if isSubscriptionCharge(account, transaction) {
+		return nil, nil
+	}
+	key := fuzzyDuplicateKey{transaction.AccountID, normalizeMerchant(transaction.Merchant)}
+	recent := f.recent[key]
+	since := transaction.Time.Add(-f.interval)
+	for len(recent) > 0 && !recent[0].time.After(since) {
+		recent = recent[1:]
+	}
+	f.recent[key] = recent
+
+	for _, past := range recent {
+		if f.equivalentAmounts(past.amount, transaction.Amount) {
+			verr := violation.NewError(violation.DoubleTransaction,
+				"Duplicate of a transaction in the same merchant with a similar amount at %v", past.time)
+			return nil, verr.WithDetails(DuplicateMatch{TransactionID: past.id, Time: past.time})
+		}
+	}
+
+	commit := func(_ *model.Account) {
+		f.recent[key] = append(f.recent[key], pastTransaction{transaction.ID, transaction.Amount, transaction.Time})
+	}
+	return commit, nil
+}
+
+func (f *FuzzyDuplicates) equivalentAmounts(earlier, amount int64) bool {
+	diff := amount - earlier
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= f.tolerance.Absolute || float64(diff) <= f.tolerance.Percentage*float64(earlier)/100
+}
+
+// normalizeMerchant returns the merchant name in lower case and only with its
+// letters and digits, so that names only differing in punctuation or spacing
+// are equal.
+func normalizeMerchant(merchant string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, merchant)
+}
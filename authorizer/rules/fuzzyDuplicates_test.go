@@ -0,0 +1,62 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var fuzzyStartTime = time.Date(2021, time.April, 11, 18, 0, 0, 0, time.UTC)
+
+func TestFuzzyDuplicates(t *testing.T) {
+	Convey("Given FuzzyDuplicates authorizer", t, func() {
+		interval := 2 * time.Minute
+		authzer := rules.NewFuzzyDuplicates(interval, rules.AmountTolerance{Absolute: 1, Percentage: 2})
+
+		test := func(id, accountID, merchant string, amount int64, diff time.Duration) error {
+			transaction := model.Transaction{ID: id, AccountID: accountID, Merchant: merchant, Amount: amount, Time: fuzzyStartTime.Add(diff)}
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+		match := func(err error) rules.DuplicateMatch {
+			var verr violation.Error
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Code, ShouldEqual, violation.DoubleTransaction)
+			return verr.Details.(rules.DuplicateMatch)
+		}
+
+		So(test("tx1", "acc", "UBER *TRIP", 1000, 0), ShouldBeNil)
+
+		Convey("It should NOT authorize equivalent merchant names", func() {
+			So(match(test("tx2", "acc", "Uber Trip", 1000, 1*time.Minute)), ShouldResemble,
+				rules.DuplicateMatch{TransactionID: "tx1", Time: fuzzyStartTime})
+		})
+		Convey("It should NOT authorize amounts within the tolerance", func() {
+			So(match(test("tx2", "acc", "uber trip", 1001, 0)).TransactionID, ShouldEqual, "tx1")
+			So(match(test("tx2", "acc", "uber trip", 980, 0)).TransactionID, ShouldEqual, "tx1")
+		})
+		Convey("It should authorize amounts outside the tolerance", func() {
+			So(test("tx2", "acc", "Uber Trip", 1021, 0), ShouldBeNil)
+			So(test("tx3", "acc", "Uber Trip", 979, 0), ShouldBeNil)
+		})
+		Convey("It should authorize other merchants and accounts", func() {
+			So(test("tx2", "acc", "Uber Eats", 1000, 0), ShouldBeNil)
+			So(test("tx3", "other", "Uber Trip", 1000, 0), ShouldBeNil)
+		})
+		Convey("It should authorize duplicates after the interval", func() {
+			So(test("tx2", "acc", "Uber Trip", 1000, interval), ShouldBeNil)
+
+			Convey("And match the latest transaction", func() {
+				So(match(test("tx3", "acc", "Uber Trip", 1000, interval+1*time.Minute)).TransactionID, ShouldEqual, "tx2")
+			})
+		})
+	})
+}
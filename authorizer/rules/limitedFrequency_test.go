@@ -28,7 +28,7 @@ func TestLimitedFrequency(t *testing.T) {
 				commitFunc, err := authzer.Authorize(model.Account{}, transaction)
 				So(commitFunc, ShouldNotBeNil)
 				So(err, ShouldBeNil)
-				commitFunc()
+				commitFunc(&model.Account{})
 			}
 
 			Convey("Then it should still authorize unlimited transactions if the commitFunc is not called", func() {
@@ -37,7 +37,7 @@ func TestLimitedFrequency(t *testing.T) {
 				}
 				testSuccess(baseTransacton)
 			})
-			commitFunc()
+			commitFunc(&model.Account{})
 
 			Convey("It SHOULD authorize", func() {
 				Convey("Up until the quota in the same timestamp", func() {
@@ -86,7 +86,7 @@ func TestLimitedFrequency(t *testing.T) {
 					commitFunc, err := authzer.Authorize(model.Account{}, transaction)
 					So(commitFunc, ShouldBeNil)
 					So(err, ShouldNotBeNil)
-					So(err, ShouldResemble, violation.ErrorHighFrequencySmallInterval)
+					So(err, shouldBeViolation, violation.ErrorHighFrequencySmallInterval)
 				}
 
 				Convey("If the quota is immediately consumed", func() {
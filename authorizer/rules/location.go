@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+	"time"
+)
+
+// CountryLists is a rule.AuthorizerFunc to restrict the countries where the
+// card of the account can be used, through the allowed and blocked country
+// lists configured in the account. It returns a country-not-allowed violation
+// error if the country of the transaction is blocked, or if the account has
+// allowed countries and the transaction country is not one of them.
+//
+// Transactions without a country in their location are not restricted.
+func CountryLists(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if transaction.Location == nil || transaction.Location.Country == "" {
+		return nil, nil
+	}
+
+	country := transaction.Location.Country
+	if containsCountry(account.BlockedCountries, country) {
+		return nil, violation.ErrorCountryNotAllowed
+	}
+	if len(account.AllowedCountries) > 0 && !containsCountry(account.AllowedCountries, country) {
+		return nil, violation.ErrorCountryNotAllowed
+	}
+	return nil, nil
+}
+
+func containsCountry(countries []string, country string) bool {
+	for _, elm := range countries {
+		if strings.EqualFold(elm, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// locatedEvent is the position and time of a transaction in some account.
+type locatedEvent struct {
+	coordinates model.Coordinates
+	time        time.Time
+}
+
+// ImpossibleTravel is a rule.Authorizer to detect cards being used in places
+// too far away from each other in a short time. It keeps the coordinates of
+// the last executed transaction of each account, and checks that the speed
+// required for travelling between that place and the next transaction's one
+// doesn't exceed a configurable maximum.
+//
+// Transactions without coordinates in their location are not analyzed.
+type ImpossibleTravel struct {
+	maxSpeedKmh float64
+	lastEvents  map[string]locatedEvent
+}
+
+// NewImpossibleTravel creates a new impossible travel authorizer, which allows
+// consecutive transactions in the same account only if they imply a speed of at
+// most `maxSpeedKmh` kilometers per hour.
+func NewImpossibleTravel(maxSpeedKmh float64) *ImpossibleTravel {
+	return &ImpossibleTravel{
+		maxSpeedKmh: maxSpeedKmh,
+		lastEvents:  map[string]locatedEvent{},
+	}
+}
+
+// Authorize checks the speed implied by the transaction and the last executed
+// one in the same account, and if it exceeds the maximum speed the transaction
+// is not authorized and an impossible-travel violation error is returned.
+func (i *ImpossibleTravel) Authorize(_ model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if transaction.Location == nil || transaction.Location.Coordinates == nil {
+		return nil, nil
+	}
+
+	current := locatedEvent{*transaction.Location.Coordinates, transaction.Time}
+	if last, ok := i.lastEvents[transaction.AccountID]; ok {
+		distance := last.coordinates.DistanceKm(current.coordinates)
+		hours := current.time.Sub(last.time).Hours()
+		if distance > i.maxSpeedKmh*hours {
+			return nil, violation.NewError(violation.ImpossibleTravel,
+				"Travelled %.0fkm in %v since the last transaction", distance, current.time.Sub(last.time))
+		}
+	}
+	commit := func(_ *model.Account) { i.lastEvents[transaction.AccountID] = current }
+	return commit, nil
+}
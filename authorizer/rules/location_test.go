@@ -0,0 +1,91 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	travelStartTime = time.Date(2021, time.April, 8, 9, 0, 0, 0, time.UTC)
+	saoPaulo        = &model.Coordinates{Latitude: -23.5505, Longitude: -46.6333}
+	rio             = &model.Coordinates{Latitude: -22.9068, Longitude: -43.1729}
+)
+
+func TestCountryLists(t *testing.T) {
+	Convey("Given CountryLists authorizer function", t, func() {
+		account := model.Account{}
+		test := func(location *model.Location) error {
+			commitFunc, err := rules.CountryLists(account, model.Transaction{Location: location})
+			So(commitFunc, ShouldBeNil)
+			return err
+		}
+
+		Convey("It should authorize any country without lists", func() {
+			So(test(&model.Location{Country: "BR"}), ShouldBeNil)
+		})
+
+		Convey("With country lists", func() {
+			account.AllowedCountries = []string{"BR", "PT", "US"}
+			account.BlockedCountries = []string{"us"}
+
+			Convey("It should authorize allowed countries", func() {
+				So(test(&model.Location{Country: "br"}), ShouldBeNil)
+			})
+			Convey("It should NOT authorize blocked countries", func() {
+				So(test(&model.Location{Country: "US"}), ShouldResemble, violation.ErrorCountryNotAllowed)
+			})
+			Convey("It should NOT authorize countries not allowed", func() {
+				So(test(&model.Location{Country: "AR"}), ShouldResemble, violation.ErrorCountryNotAllowed)
+			})
+			Convey("It should authorize transactions without a country", func() {
+				So(test(nil), ShouldBeNil)
+				So(test(&model.Location{Coordinates: saoPaulo}), ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	Convey("Given ImpossibleTravel authorizer", t, func() {
+		authzer := rules.NewImpossibleTravel(500)
+
+		test := func(accountID string, coordinates *model.Coordinates, diff time.Duration) error {
+			transaction := model.Transaction{AccountID: accountID, Time: travelStartTime.Add(diff)}
+			if coordinates != nil {
+				transaction.Location = &model.Location{Coordinates: coordinates}
+			}
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+		isImpossible := func(err error) bool {
+			var verr violation.Error
+			return errors.As(err, &verr) && verr.Code == violation.ImpossibleTravel
+		}
+
+		So(test("acc", saoPaulo, 0), ShouldBeNil)
+
+		Convey("It should authorize transactions in the same place", func() {
+			So(test("acc", saoPaulo, 0), ShouldBeNil)
+		})
+		Convey("It should authorize travelling at a possible speed", func() {
+			So(test("acc", rio, 1*time.Hour), ShouldBeNil)
+		})
+		Convey("It should NOT authorize travelling too fast", func() {
+			So(isImpossible(test("acc", rio, 30*time.Minute)), ShouldBeTrue)
+			So(isImpossible(test("acc", rio, 0)), ShouldBeTrue)
+		})
+		Convey("It should authorize other accounts and transactions without coordinates", func() {
+			So(test("other", rio, 0), ShouldBeNil)
+			So(test("acc", nil, 0), ShouldBeNil)
+		})
+	})
+}
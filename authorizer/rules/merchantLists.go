@@ -0,0 +1,62 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"strings"
+)
+
+// MerchantLists is a rule.AuthorizerFunc to restrict where the card of the
+// account can be used, through the merchant allowlist and blocklist configured
+// in the account. It returns a merchant-not-allowed violation error if the
+// merchant of the transaction matches any pattern in the blocklist, or if the
+// account has an allowlist and the merchant matches none of its patterns.
+//
+// The patterns are matched against the merchant names case-insensitively and
+// may contain `*` wildcards matching any sequence of characters, so that
+// "uber*" matches both "Uber Trip" and "UBER *EATS".
+func MerchantLists(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if matchAnyMerchant(account.MerchantBlocklist, transaction.Merchant) {
+		return nil, violation.ErrorMerchantNotAllowed
+	}
+	if len(account.MerchantAllowlist) > 0 && !matchAnyMerchant(account.MerchantAllowlist, transaction.Merchant) {
+		return nil, violation.ErrorMerchantNotAllowed
+	}
+	return nil, nil
+}
+
+// matchAnyMerchant returns whether the merchant matches any of the patterns.
+func matchAnyMerchant(patterns []string, merchant string) bool {
+	for _, pattern := range patterns {
+		if matchMerchant(pattern, merchant) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMerchant returns whether the merchant name matches the given pattern,
+// ignoring case and with `*` matching any sequence of characters.
+func matchMerchant(pattern, merchant string) bool {
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	name := strings.ToLower(merchant)
+
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	if len(parts) == 1 {
+		return name == ""
+	}
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(name, part)
+		if idx < 0 {
+			return false
+		}
+		name = name[idx+len(part):]
+	}
+	return strings.HasSuffix(name, last)
+}
@@ -0,0 +1,57 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMerchantLists(t *testing.T) {
+	Convey("Given MerchantLists authorizer function", t, func() {
+		account := model.Account{}
+		test := func(merchant string) error {
+			commitFunc, err := rules.MerchantLists(account, model.Transaction{Merchant: merchant})
+			So(commitFunc, ShouldBeNil)
+			return err
+		}
+
+		Convey("It should authorize any merchant without lists", func() {
+			So(test("Anything"), ShouldBeNil)
+		})
+
+		Convey("With a blocklist", func() {
+			account.MerchantBlocklist = []string{"casino*", "*bet*", "Uber Eats"}
+
+			Convey("It should NOT authorize matching merchants", func() {
+				So(test("Casino Royale"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+				So(test("SportsBet Online"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+				So(test("UBER EATS"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+			})
+			Convey("It should authorize other merchants", func() {
+				So(test("Uber Eats Delivery"), ShouldBeNil)
+				So(test("Royal Casino"), ShouldBeNil)
+			})
+		})
+
+		Convey("With an allowlist", func() {
+			account.MerchantAllowlist = []string{"uber*trip", "Bookstore"}
+
+			Convey("It should authorize matching merchants", func() {
+				So(test("UBER *TRIP"), ShouldBeNil)
+				So(test("Uber Trip"), ShouldBeNil)
+				So(test("bookstore"), ShouldBeNil)
+			})
+			Convey("It should NOT authorize other merchants", func() {
+				So(test("Uber Trip Help"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+				So(test("Bookstore Cafe"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+			})
+			Convey("It should still NOT authorize blocked merchants", func() {
+				account.MerchantBlocklist = []string{"*"}
+				So(test("Bookstore"), ShouldResemble, violation.ErrorMerchantNotAllowed)
+			})
+		})
+	})
+}
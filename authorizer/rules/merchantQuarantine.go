@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"time"
+)
+
+// MerchantQuarantine is a rule.Authorizer to detect compromised merchants, by
+// limiting the frequency of transactions in each merchant across all the
+// accounts of the ledger. When a burst of transactions exceeds the configured
+// threshold, the merchant is put in quarantine for some time and every
+// transaction attempted in it is declined until the quarantine is over.
+//
+// The quarantine is started by the decline action of the transaction that
+// exceeded the threshold, so it only happens when the transaction is actually
+// declined (e.g. not when the rule runs in shadow mode).
+type MerchantQuarantine struct {
+	baseLimiter util.RateLimiter
+	duration    time.Duration
+	limiters    map[string]*util.RateLimiter
+	quarantines map[string]time.Time
+}
+
+// NewMerchantQuarantine creates a new merchant quarantine authorizer, which
+// allows at most `maxTransactions` in the same merchant within the `interval`
+// across all the accounts. When that threshold is exceeded, the merchant is
+// quarantined for the given `duration`.
+func NewMerchantQuarantine(maxTransactions int, interval, duration time.Duration) *MerchantQuarantine {
+	return &MerchantQuarantine{
+		baseLimiter: util.RateLimiter{MaxEvents: maxTransactions, Interval: interval},
+		duration:    duration,
+		limiters:    map[string]*util.RateLimiter{},
+		quarantines: map[string]time.Time{},
+	}
+}
+
+// Authorize checks if the merchant of the transaction is in quarantine or if
+// the transaction exceeds the merchant's frequency threshold. In both cases the
+// transaction is not authorized and a merchant-quarantined violation error is
+// returned, in the latter case as a rule.ActionError with an action to start a
+// new quarantine.
+func (m *MerchantQuarantine) Authorize(_ model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	merchant := transaction.Merchant
+	if until, ok := m.quarantines[merchant]; ok && transaction.Time.Before(until) {
+		return nil, quarantineViolation(until)
+	}
+
+	limiter := m.getLimiter(merchant)
+	if !limiter.Allows(transaction.Time) {
+		until := transaction.Time.Add(m.duration)
+		quarantine := func(_ *model.Account) { m.quarantines[merchant] = until }
+		return nil, rule.ActionError{Err: quarantineViolation(until), Action: quarantine}
+	}
+	commit := func(_ *model.Account) { limiter.Take(transaction.Time) }
+	return commit, nil
+}
+
+// getLimiter tries to get the existing rate limiter for a given merchant and
+// creates a new one if there is none yet.
+func (m *MerchantQuarantine) getLimiter(merchant string) *util.RateLimiter {
+	limiter := m.limiters[merchant]
+	if limiter != nil {
+		return limiter
+	}
+
+	copy := m.baseLimiter
+	limiter = &copy
+	m.limiters[merchant] = limiter
+	return limiter
+}
+
+func quarantineViolation(until time.Time) violation.Error {
+	return violation.NewError(violation.MerchantQuarantined, "Merchant is quarantined until %v", until)
+}
@@ -0,0 +1,71 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rule"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var quarantineStartTime = time.Date(2021, time.April, 7, 15, 0, 0, 0, time.UTC)
+
+func TestMerchantQuarantine(t *testing.T) {
+	Convey("Given MerchantQuarantine authorizer", t, func() {
+		interval, duration := 1*time.Minute, 1*time.Hour
+		authzer := rules.NewMerchantQuarantine(3, interval, duration)
+
+		authorize := func(accountID, merchant string, diff time.Duration) (rule.CommitFunc, error) {
+			transaction := model.Transaction{AccountID: accountID, Merchant: merchant, Time: quarantineStartTime.Add(diff)}
+			return authzer.Authorize(model.Account{}, transaction)
+		}
+		test := func(accountID, merchant string, diff time.Duration) error {
+			commitFunc, err := authorize(accountID, merchant, diff)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			for _, action := range rule.DeclineActions(err) {
+				action(&model.Account{})
+			}
+			return err
+		}
+		isQuarantined := func(err error) bool {
+			var verr violation.Error
+			return errors.As(err, &verr) && verr.Code == violation.MerchantQuarantined
+		}
+
+		Convey("When a burst of transactions happens across accounts", func() {
+			So(test("a", "Compromised", 0), ShouldBeNil)
+			So(test("b", "Compromised", 0), ShouldBeNil)
+			So(test("c", "Compromised", 0), ShouldBeNil)
+
+			Convey("It should still authorize other merchants", func() {
+				So(test("d", "Fine Shop", 0), ShouldBeNil)
+			})
+
+			Convey("It should quarantine the merchant when exceeding the threshold", func() {
+				So(isQuarantined(test("d", "Compromised", 1*time.Second)), ShouldBeTrue)
+
+				Convey("And decline any transactions until the quarantine is over", func() {
+					So(isQuarantined(test("e", "Compromised", duration)), ShouldBeTrue)
+					So(test("e", "Compromised", duration+1*time.Second), ShouldBeNil)
+				})
+			})
+
+			Convey("It should only start the quarantine when the transaction is declined", func() {
+				_, err := authorize("d", "Compromised", 1*time.Second)
+				So(isQuarantined(err), ShouldBeTrue)
+
+				So(test("e", "Compromised", interval), ShouldBeNil)
+			})
+
+			Convey("It should not quarantine if the burst is over", func() {
+				So(test("d", "Compromised", interval), ShouldBeNil)
+			})
+		})
+	})
+}
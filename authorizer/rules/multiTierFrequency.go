@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+)
+
+// MultiTierFrequency is a rule.Authorizer to guarantee that transactions do not
+// exceed any of multiple maximum allowed frequencies on each account, e.g. at
+// most 3 transactions every 2 minutes, 10 every hour and 30 every day. All the
+// tiers share a single history of transactions per account through a
+// util.TieredRateLimiter, instead of each tier keeping its own. Recurring
+// charges of registered subscriptions are not limited.
+type MultiTierFrequency struct {
+	tiers  []util.RateTier
+	groups map[string]*tieredGroup
+}
+
+// tieredGroup is the state of the transactions of an account analyzed by the
+// MultiTierFrequency.
+type tieredGroup struct {
+	limiter *util.TieredRateLimiter
+	last    DuplicateMatch
+}
+
+// NewMultiTierFrequency creates a new multi-tier frequency authorizer which
+// limits the transactions of each account by all the given `tiers`.
+func NewMultiTierFrequency(tiers ...util.RateTier) *MultiTierFrequency {
+	return &MultiTierFrequency{
+		tiers:  tiers,
+		groups: map[string]*tieredGroup{},
+	}
+}
+
+// Authorize checks if any of the tiers would be exceeded by the transaction,
+// and if so the transaction is not authorized and a violation error of
+// high-frequency-small-interval is returned. The violation has the same
+// FrequencyDetails as the ones of the FrequencyAnalyzer, with the time when all
+// the tiers would allow a transaction again.
+func (m *MultiTierFrequency) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if isSubscriptionCharge(account, transaction) {
+		return nil, nil
+	}
+
+	event := transaction.Time
+	group := m.getGroup(transaction.AccountID)
+	if idx := group.limiter.Exceeded(event); idx >= 0 {
+		tier := m.tiers[idx]
+		details := &FrequencyDetails{LastTransaction: group.last}
+		if retryAfter, ok := group.limiter.RetryAfter(event); ok {
+			details.RetryAfter = &retryAfter
+		}
+		return nil, violation.NewError(violation.HighFrequencySmallInterval,
+			"Too many transactions: more than %d in %v", tier.MaxEvents, tier.Interval).
+			WithDetails(details)
+	}
+	commit := func(_ *model.Account) {
+		group.limiter.Take(event)
+		group.last = DuplicateMatch{TransactionID: transaction.ID, Time: transaction.Time}
+	}
+	return commit, nil
+}
+
+// getGroup tries to get the existing group for a given account and creates a
+// new one with its rate limiter if there is none yet.
+func (m *MultiTierFrequency) getGroup(accountID string) *tieredGroup {
+	group := m.groups[accountID]
+	if group != nil {
+		return group
+	}
+
+	group = &tieredGroup{limiter: &util.TieredRateLimiter{Tiers: m.tiers}}
+	m.groups[accountID] = group
+	return group
+}
@@ -0,0 +1,64 @@
+package rules_test
+
+import (
+	"errors"
+	"fmt"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultiTierFrequency(t *testing.T) {
+	Convey("Given MultiTierFrequency authorizer", t, func() {
+		tiers := []util.RateTier{
+			{MaxEvents: 2, Interval: 2 * time.Minute},
+			{MaxEvents: 3, Interval: 1 * time.Hour},
+		}
+		authzer := rules.NewMultiTierFrequency(tiers...)
+
+		count := 0
+		test := func(accountID string, diff time.Duration) error {
+			count++
+			transaction := model.Transaction{ID: fmt.Sprint(count), AccountID: accountID, Time: frequencyStartTime.Add(diff)}
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+		testTierExceeded := func(err error, tier util.RateTier, retryAfter time.Duration, last rules.DuplicateMatch) {
+			var verr violation.Error
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Code, ShouldEqual, violation.HighFrequencySmallInterval)
+			So(verr.Message, ShouldContainSubstring, fmt.Sprintf("more than %d in %v", tier.MaxEvents, tier.Interval))
+
+			retryTime := frequencyStartTime.Add(retryAfter)
+			So(verr.Details, ShouldResemble, &rules.FrequencyDetails{RetryAfter: &retryTime, LastTransaction: last})
+		}
+
+		So(test("acc", 0), ShouldBeNil)
+		So(test("acc", 0), ShouldBeNil)
+
+		Convey("It should report the short tier as exceeded", func() {
+			testTierExceeded(test("acc", 1*time.Minute), tiers[0], 2*time.Minute,
+				rules.DuplicateMatch{TransactionID: "2", Time: frequencyStartTime})
+		})
+		Convey("It should not limit other accounts", func() {
+			So(test("other", 1*time.Minute), ShouldBeNil)
+		})
+		Convey("It should report the long tier as exceeded", func() {
+			So(test("acc", 2*time.Minute), ShouldBeNil)
+			testTierExceeded(test("acc", 10*time.Minute), tiers[1], 1*time.Hour,
+				rules.DuplicateMatch{TransactionID: "3", Time: frequencyStartTime.Add(2 * time.Minute)})
+
+			Convey("Until the long interval passes", func() {
+				So(test("acc", 1*time.Hour), ShouldBeNil)
+			})
+		})
+	})
+}
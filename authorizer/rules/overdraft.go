@@ -0,0 +1,35 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+)
+
+// NewOverdraft returns a rule.Authorizer to be used as an alternative to the
+// SufficientLimit rule, which allows the available limit of an account to go
+// negative up to the account's own OverdraftLimit. Every transaction that makes
+// use of the overdraft, i.e. leaves the available limit below zero, is also
+// charged the given `fee` by the returned CommitFunc.
+//
+// Its Authorize function returns an insufficient-limit violation error for any
+// account without an overdraft allowance, or an overdraft-limit-exceeded
+// violation error if the transaction amount plus the fee would take the account
+// beyond its allowance. Both of them have the LimitDetails of the account.
+func NewOverdraft(fee int64) rule.Authorizer {
+	return rule.AuthorizerFunc(func(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+		balance := account.AvailableLimit - transaction.Amount
+		if balance >= 0 {
+			return nil, nil
+		}
+		if account.OverdraftLimit <= 0 {
+			return nil, violation.ErrorInsufficientLimit.WithDetails(&LimitDetails{Remaining: account.AvailableLimit})
+		}
+		if balance-fee < -account.OverdraftLimit {
+			remaining := account.AvailableLimit + account.OverdraftLimit - fee
+			return nil, violation.ErrorOverdraftLimitExceeded.WithDetails(&LimitDetails{Remaining: remaining})
+		}
+		chargeFee := func(account *model.Account) { account.AvailableLimit -= fee }
+		return chargeFee, nil
+	})
+}
@@ -0,0 +1,55 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOverdraft(t *testing.T) {
+	Convey("Given Overdraft authorizer", t, func() {
+		fee := int64(5)
+		authzer := rules.NewOverdraft(fee)
+		account := model.Account{AvailableLimit: 50, OverdraftLimit: 100}
+
+		Convey("It should authorize transactions within the available limit", func() {
+			commitFunc, err := authzer.Authorize(account, model.Transaction{Amount: 50})
+			So(commitFunc, ShouldBeNil)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("It should authorize transactions within the overdraft allowance", func() {
+			commitFunc, err := authzer.Authorize(account, model.Transaction{Amount: 145})
+			So(err, ShouldBeNil)
+			So(commitFunc, ShouldNotBeNil)
+
+			Convey("And charge the overdraft fee when committed", func() {
+				committed := account
+				committed.AvailableLimit -= 145
+				commitFunc(&committed)
+				So(committed.AvailableLimit, ShouldEqual, -100)
+			})
+		})
+
+		Convey("It should NOT authorize transactions exceeding the overdraft allowance", func() {
+			_, err := authzer.Authorize(account, model.Transaction{Amount: 146})
+			So(err, ShouldNotBeNil)
+			So(err, shouldBeViolation, violation.ErrorOverdraftLimitExceeded)
+
+			var verr violation.Error
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Details, ShouldResemble, &rules.LimitDetails{Remaining: 145})
+		})
+
+		Convey("It should NOT authorize accounts without overdraft beyond their limit", func() {
+			account.OverdraftLimit = 0
+			_, err := authzer.Authorize(account, model.Transaction{Amount: 51})
+			So(err, ShouldNotBeNil)
+			So(err, shouldBeViolation, violation.ErrorInsufficientLimit)
+		})
+	})
+}
@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"strings"
+	"time"
+)
+
+const (
+	// riskMinHistory is the minimum number of past transactions of an account
+	// for its amounts to be compared against the new ones.
+	riskMinHistory = 5
+	// riskUnusualAmountStdDevs is how many standard deviations above the mean
+	// an amount must be to be considered unusual, with the standard deviation
+	// floored like in AmountAnomaly.
+	riskUnusualAmountStdDevs = 3
+	// riskVelocityMaxTransactions is how many transactions in the velocity
+	// interval are allowed before the next ones are considered high velocity.
+	riskVelocityMaxTransactions = 5
+	riskVelocityInterval        = 1 * time.Hour
+	// riskNightEndHour is the hour of the day when the night ends. Night-time
+	// starts at midnight.
+	riskNightEndHour = 6
+)
+
+// riskProfile is the history of the executed transactions of an account, used
+// to detect the signals about the risk of its new transactions.
+type riskProfile struct {
+	merchants map[string]bool
+	amounts   util.RunningStats
+	velocity  util.RateLimiter
+}
+
+// RiskScore is a rule.Authorizer that combines multiple signals about the risk
+// of a transaction, like being in a new merchant or at night-time, into a
+// numeric risk score instead of rejecting transactions on any single one of
+// them. Each signal present in the transaction adds its configured weight to
+// the score, and transactions with a score above the threshold are declined.
+//
+// The signals that depend on the history of the account only consider the
+// transactions that were actually executed, and the night-time is in the
+// account time zone.
+//
+// It is a rule.Assessor, so the assessment of the transactions it allows can
+// be reported too.
+type RiskScore struct {
+	weights     map[model.RiskSignal]float64
+	threshold   float64
+	profiles    map[string]*riskProfile
+	assessments map[string]*model.RiskAssessment
+}
+
+// NewRiskScore creates a new risk score authorizer, which scores transactions
+// with the given `weights` for each signal and declines the ones with a score
+// higher than `threshold`. Signals missing from the weights are ignored.
+func NewRiskScore(weights map[model.RiskSignal]float64, threshold float64) *RiskScore {
+	return &RiskScore{
+		weights:     weights,
+		threshold:   threshold,
+		profiles:    map[string]*riskProfile{},
+		assessments: map[string]*model.RiskAssessment{},
+	}
+}
+
+// Authorize calculates the risk score of the transaction, and if it is above
+// the threshold the transaction is not authorized and a high-risk violation
+// error is returned. The error has the *model.RiskAssessment with the score and
+// the contributing signals as its details. In either case, the assessment is
+// kept until taken with TakeAssessment.
+func (r *RiskScore) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	local, err := localTime(account, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := r.getProfile(transaction.AccountID)
+	assessment := model.RiskAssessment{Signals: []model.RiskSignal{}}
+	for _, signal := range profile.detectSignals(account, transaction, local) {
+		if weight := r.weights[signal]; weight != 0 {
+			assessment.Score += weight
+			assessment.Signals = append(assessment.Signals, signal)
+		}
+	}
+	r.assessments[transaction.AccountID] = &assessment
+	if assessment.Score > r.threshold {
+		verr := violation.NewError(violation.HighRisk,
+			"Transaction risk score %.2f is above the threshold of %.2f", assessment.Score, r.threshold)
+		return nil, verr.WithDetails(&assessment)
+	}
+
+	commit := func(_ *model.Account) {
+		profile.merchants[strings.ToLower(transaction.Merchant)] = true
+		profile.amounts.Add(float64(transaction.Amount))
+		profile.velocity.Take(transaction.Time)
+	}
+	return commit, nil
+}
+
+// TakeAssessment implements the rule.Assessor interface, returning the
+// assessment of the last transaction of the account authorized by the rule.
+func (r *RiskScore) TakeAssessment(accountID string) *model.RiskAssessment {
+	assessment := r.assessments[accountID]
+	delete(r.assessments, accountID)
+	return assessment
+}
+
+// getProfile tries to get the existing risk profile for a given account and
+// creates a new one if there is none yet.
+func (r *RiskScore) getProfile(accountID string) *riskProfile {
+	profile := r.profiles[accountID]
+	if profile != nil {
+		return profile
+	}
+
+	profile = &riskProfile{
+		merchants: map[string]bool{},
+		velocity:  util.RateLimiter{MaxEvents: riskVelocityMaxTransactions, Interval: riskVelocityInterval},
+	}
+	r.profiles[accountID] = profile
+	return profile
+}
+
+// detectSignals returns all the risk signals present in the transaction, given
+// the history of the account and the transaction local time.
+func (p *riskProfile) detectSignals(account model.Account, transaction model.Transaction, local time.Time) []model.RiskSignal {
+	var signals []model.RiskSignal
+	if !p.merchants[strings.ToLower(transaction.Merchant)] {
+		signals = append(signals, model.RiskSignalNewMerchant)
+	}
+	if p.amounts.Count() >= riskMinHistory &&
+		isUnusualAmount(&p.amounts, float64(transaction.Amount), riskUnusualAmountStdDevs) {
+		signals = append(signals, model.RiskSignalUnusualAmount)
+	}
+	if !p.velocity.Allows(transaction.Time) {
+		signals = append(signals, model.RiskSignalHighVelocity)
+	}
+	if local.Hour() < riskNightEndHour {
+		signals = append(signals, model.RiskSignalNightTime)
+	}
+	if loc := transaction.Location; loc != nil && loc.Country != "" && account.HomeCountry != "" &&
+		!strings.EqualFold(loc.Country, account.HomeCountry) {
+		signals = append(signals, model.RiskSignalForeignCountry)
+	}
+	return signals
+}
@@ -0,0 +1,115 @@
+package rules_test
+
+import (
+	"errors"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var riskStartTime = time.Date(2021, time.April, 9, 12, 0, 0, 0, time.UTC)
+
+func TestRiskScore(t *testing.T) {
+	Convey("Given RiskScore authorizer", t, func() {
+		weights := map[model.RiskSignal]float64{
+			model.RiskSignalNewMerchant:    10,
+			model.RiskSignalUnusualAmount:  40,
+			model.RiskSignalHighVelocity:   30,
+			model.RiskSignalNightTime:      20,
+			model.RiskSignalForeignCountry: 25,
+		}
+		authzer := rules.NewRiskScore(weights, 50)
+		account := model.Account{HomeCountry: "BR"}
+
+		test := func(merchant string, amount int64, diff time.Duration, country string) error {
+			transaction := model.Transaction{Merchant: merchant, Amount: amount, Time: riskStartTime.Add(diff)}
+			if country != "" {
+				transaction.Location = &model.Location{Country: country}
+			}
+			commitFunc, err := authzer.Authorize(account, transaction)
+			if commitFunc != nil {
+				commitFunc(&account)
+			}
+			return err
+		}
+		assessment := func(err error) *model.RiskAssessment {
+			var verr violation.Error
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Code, ShouldEqual, violation.HighRisk)
+			return verr.Details.(*model.RiskAssessment)
+		}
+
+		Convey("It should authorize transactions below the threshold", func() {
+			So(test("Bakery", 10, 0, "BR"), ShouldBeNil)
+			So(test("Bakery", 10, 1*time.Hour, "AR"), ShouldBeNil)
+		})
+
+		Convey("It should keep the assessment of the last transaction", func() {
+			So(test("Bakery", 10, 0, "AR"), ShouldBeNil)
+			So(authzer.TakeAssessment(""), ShouldResemble, &model.RiskAssessment{
+				Score:   35,
+				Signals: []model.RiskSignal{model.RiskSignalNewMerchant, model.RiskSignalForeignCountry},
+			})
+			So(authzer.TakeAssessment(""), ShouldBeNil)
+
+			result := assessment(test("Casino", 10, 14*time.Hour, "US"))
+			So(authzer.TakeAssessment(""), ShouldEqual, result)
+		})
+
+		Convey("It should NOT authorize transactions above the threshold", func() {
+			So(test("Bakery", 10, 0, "BR"), ShouldBeNil)
+			result := assessment(test("Casino", 10, 14*time.Hour, "US"))
+			So(result.Score, ShouldEqual, 55)
+			So(result.Signals, ShouldResemble, []model.RiskSignal{
+				model.RiskSignalNewMerchant, model.RiskSignalNightTime, model.RiskSignalForeignCountry,
+			})
+		})
+
+		Convey("It should use the account time zone for the night-time", func() {
+			account.TimeZone = "America/Sao_Paulo"
+			So(test("Casino", 10, 14*time.Hour, "US"), ShouldBeNil)
+		})
+
+		Convey("With some account history", func() {
+			for i := 0; i < 5; i++ {
+				So(test("Bakery", int64(10+i), time.Duration(i)*time.Minute, ""), ShouldBeNil)
+			}
+
+			Convey("It should detect high velocity", func() {
+				result := assessment(test("Pharmacy", 10, 10*time.Minute, "US"))
+				So(result.Score, ShouldEqual, 65)
+				So(result.Signals, ShouldResemble, []model.RiskSignal{
+					model.RiskSignalNewMerchant, model.RiskSignalHighVelocity, model.RiskSignalForeignCountry,
+				})
+				So(test("Pharmacy", 10, 1*time.Hour, "US"), ShouldBeNil)
+			})
+
+			Convey("It should detect unusual amounts", func() {
+				So(test("Bakery", 15, 2*time.Hour, "US"), ShouldBeNil)
+				result := assessment(test("Jewelry", 1000, 2*time.Hour, "US"))
+				So(result.Score, ShouldEqual, 75)
+				So(result.Signals, ShouldResemble, []model.RiskSignal{
+					model.RiskSignalNewMerchant, model.RiskSignalUnusualAmount, model.RiskSignalForeignCountry,
+				})
+			})
+		})
+
+		Convey("With a history of always the same amount", func() {
+			for i := 0; i < 5; i++ {
+				So(test("Bakery", 100, time.Duration(i)*time.Hour, ""), ShouldBeNil)
+			}
+
+			Convey("It should NOT consider slightly higher amounts unusual", func() {
+				So(test("Bakery", 101, 5*time.Hour, "US"), ShouldBeNil)
+			})
+			Convey("It should still detect unusual amounts", func() {
+				result := assessment(test("Bakery", 150, 5*time.Hour, "US"))
+				So(result.Signals, ShouldResemble, []model.RiskSignal{model.RiskSignalUnusualAmount, model.RiskSignalForeignCountry})
+			})
+		})
+	})
+}
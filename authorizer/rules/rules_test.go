@@ -2,6 +2,7 @@ package rules_test
 
 import (
 	"errors"
+	"fmt"
 	"nuledger/authorizer/rules"
 	"nuledger/model"
 	"nuledger/model/violation"
@@ -40,7 +41,11 @@ func TestSufficientLimit(t *testing.T) {
 		Convey("It should NOT authorize accounts with an insufficient limit", func() {
 			_, err := rules.SufficientLimit(model.Account{AvailableLimit: 50}, model.Transaction{Amount: 100})
 			So(err, ShouldNotBeNil)
-			So(err, ShouldResemble, violation.ErrorInsufficientLimit)
+			So(err, shouldBeViolation, violation.ErrorInsufficientLimit)
+
+			var verr violation.Error
+			So(errors.As(err, &verr), ShouldBeTrue)
+			So(verr.Details, ShouldResemble, &rules.LimitDetails{Remaining: 50})
 		})
 	})
 }
@@ -72,3 +77,13 @@ func TestChronologicalOrder(t *testing.T) {
 		})
 	})
 }
+
+// shouldBeViolation is an assertion for errors matching the expected violation
+// error with errors.Is, regardless of its message and details.
+func shouldBeViolation(actual interface{}, expected ...interface{}) string {
+	err, _ := actual.(error)
+	if !errors.Is(err, expected[0].(error)) {
+		return fmt.Sprintf("Expected: violation %#v\nActual:   %#v", expected[0], actual)
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+)
+
+// AllowedSchedule is a rule.AuthorizerFunc to restrict the times when the card
+// of the account can be used, through the schedule configured in the account.
+// It returns an outside-allowed-schedule violation error if the transaction
+// time, in the account time zone, is not in any of the schedule windows or is
+// on one of its holidays.
+//
+// Accounts without a schedule are not restricted.
+func AllowedSchedule(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if account.Schedule == nil {
+		return nil, nil
+	}
+
+	local, err := localTime(account, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if !account.Schedule.Allows(local) {
+		return nil, violation.ErrorOutsideAllowedSchedule
+	}
+	return nil, nil
+}
@@ -0,0 +1,62 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAllowedSchedule(t *testing.T) {
+	Convey("Given AllowedSchedule authorizer function", t, func() {
+		account := model.Account{}
+		// April 8th 2021 is a Thursday
+		test := func(hour int) error {
+			transaction := model.Transaction{Time: time.Date(2021, time.April, 8, hour, 30, 0, 0, time.UTC)}
+			commitFunc, err := rules.AllowedSchedule(account, transaction)
+			So(commitFunc, ShouldBeNil)
+			return err
+		}
+
+		Convey("It should authorize any time without a schedule", func() {
+			So(test(3), ShouldBeNil)
+		})
+
+		Convey("With a schedule with only holidays", func() {
+			account.Schedule = &model.Schedule{Holidays: []string{"2021-04-08"}}
+			So(test(10), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+
+			account.Schedule.Holidays = []string{"2021-04-09"}
+			So(test(3), ShouldBeNil)
+		})
+
+		Convey("With a business hours schedule", func() {
+			account.Schedule = &model.Schedule{
+				Windows: []model.ScheduleWindow{{Weekdays: []string{"thursday"}, From: 9, To: 18}},
+			}
+
+			Convey("It should authorize transactions within the schedule", func() {
+				So(test(9), ShouldBeNil)
+				So(test(17), ShouldBeNil)
+			})
+			Convey("It should NOT authorize transactions outside the schedule", func() {
+				So(test(8), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+				So(test(18), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+			})
+			Convey("It should NOT authorize transactions on holidays", func() {
+				account.Schedule.Holidays = []string{"2021-04-08"}
+				So(test(10), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+			})
+			Convey("It should use the account time zone", func() {
+				account.TimeZone = "America/Sao_Paulo"
+				So(test(11), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+				So(test(12), ShouldBeNil)
+				So(test(20), ShouldBeNil)
+				So(test(21), ShouldResemble, violation.ErrorOutsideAllowedSchedule)
+			})
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"time"
+)
+
+// AmountLimiter is the interface of the limiters used by the SpendAnalyzer for
+// limiting the amount spent in transactions. It is implemented by both the
+// rolling util.SpendLimiter and the calendar-aligned util.CalendarSpendLimiter.
+type AmountLimiter interface {
+	Allows(event time.Time, amount int64) bool
+	Take(event time.Time, amount int64) bool
+}
+
+// remainingLimiter is implemented by the amount limiters that can tell how much
+// can still be spent, like util.SpendLimiter.
+type remainingLimiter interface {
+	Remaining(event time.Time) int64
+}
+
+// SpendAnalyzer is a generic rule.Authorizer that can be used for any kind of
+// authorization rule based on the total amount spent in transactions within a
+// time window, given a certain constraint.
+//
+// It works exactly like the FrequencyAnalyzer, grouping the transactions with
+// a key-mapper function so that each group is limited independently, but the
+// limiting is made via an AmountLimiter which sums the amounts of the
+// transactions instead of only counting them. Transactions can also be exempted
+// from the analysis by the limiter factory in the same way. Its violations have
+// the LimitDetails if the limiter has a Remaining method.
+type SpendAnalyzer struct {
+	newLimiter func(*model.Transaction) AmountLimiter
+	keyMapper  func(*model.Transaction) interface{}
+	limiters   map[interface{}]AmountLimiter
+	violation  violation.Error
+}
+
+// NewSpendAnalyzer creates a new spend analyzer authorizer which limits the
+// amount spent by the received transactions within their corresponding group.
+// The arguments are analogous to the ones from NewFrequencyAnalyzer, with the
+// `baseLimiter` being copied when a new transaction group is created.
+func NewSpendAnalyzer(baseLimiter util.SpendLimiter, keyMapper func(*model.Transaction) interface{}, violation violation.Error) *SpendAnalyzer {
+	newLimiter := func(_ *model.Transaction) AmountLimiter {
+		copy := baseLimiter
+		return &copy
+	}
+	return NewSpendAnalyzerFunc(newLimiter, keyMapper, violation)
+}
+
+// NewSpendAnalyzerFunc creates a new spend analyzer authorizer just like
+// NewSpendAnalyzer, but receiving a `newLimiter` factory function to create the
+// limiter of each new transaction group from its first transaction, so any
+// AmountLimiter can be used. If the factory returns nil, the transaction is
+// exempt and always authorized.
+func NewSpendAnalyzerFunc(newLimiter func(*model.Transaction) AmountLimiter, keyMapper func(*model.Transaction) interface{}, violation violation.Error) *SpendAnalyzer {
+	return &SpendAnalyzer{
+		newLimiter: newLimiter,
+		keyMapper:  keyMapper,
+		limiters:   map[interface{}]AmountLimiter{},
+		violation:  violation,
+	}
+}
+
+// Authorize checks if the amount of the given transaction exceeds the limit of
+// its corresponding transaction group, and if so the transaction is not
+// authorized and the violation error configured for this analyzer is returned.
+func (s *SpendAnalyzer) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	limiter := s.getLimiter(&transaction)
+	if limiter == nil {
+		return nil, nil
+	}
+	event, err := limiterTime(limiter, account, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if !limiter.Allows(event, transaction.Amount) {
+		if limiter, ok := limiter.(remainingLimiter); ok {
+			return nil, s.violation.WithDetails(&LimitDetails{Remaining: limiter.Remaining(event)})
+		}
+		return nil, s.violation
+	}
+	commit := func(_ *model.Account) { limiter.Take(event, transaction.Amount) }
+	return commit, nil
+}
+
+// getLimiter tries to get the existing spend limiter for a given transaction
+// and creates a new one if there is none yet. It returns nil if the transaction
+// is exempt from the analysis.
+func (s *SpendAnalyzer) getLimiter(transaction *model.Transaction) AmountLimiter {
+	key := s.keyMapper(transaction)
+	limiter := s.limiters[key]
+	if limiter != nil {
+		return limiter
+	}
+
+	limiter = s.newLimiter(transaction)
+	if limiter == nil {
+		return nil
+	}
+	s.limiters[key] = limiter
+	return limiter
+}
@@ -0,0 +1,25 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"time"
+)
+
+// NewSpendLimit returns a rule.Authorizer to guarantee that the total amount
+// spent by each account does not exceed a maximum within a rolling interval,
+// e.g. at most 5000 units of currency every 24 hours. Both the `maxAmount` and
+// the sliding `interval` can be configured through the constructor arguments.
+//
+// Its Authorize function checks if the amount of the transaction would exceed
+// the maximum allowed amount in the interval, and if so the transaction is not
+// authorized and a spend-limit-exceeded violation error is returned.
+func NewSpendLimit(maxAmount int64, interval time.Duration) rule.Authorizer {
+	limiter := util.SpendLimiter{MaxAmount: maxAmount, Interval: interval}
+	keyMapper := func(tx *model.Transaction) interface{} {
+		return tx.AccountID
+	}
+	return NewSpendAnalyzer(limiter, keyMapper, violation.ErrorSpendLimitExceeded)
+}
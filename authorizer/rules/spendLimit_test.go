@@ -0,0 +1,66 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var spendStartTime = time.Date(2021, time.April, 5, 9, 30, 0, 0, time.UTC)
+
+func TestSpendLimit(t *testing.T) {
+	Convey("Given SpendLimit authorizer", t, func() {
+		maxAmount := int64(5000)
+		interval := 24 * time.Hour
+		authzer := rules.NewSpendLimit(maxAmount, interval)
+
+		genSpend := func(diff time.Duration, amount int64) model.Transaction {
+			return model.Transaction{AccountID: "spender", Merchant: "Shop", Amount: amount, Time: spendStartTime.Add(diff)}
+		}
+		testSuccess := func(transaction model.Transaction) {
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			So(err, ShouldBeNil)
+			So(commitFunc, ShouldNotBeNil)
+			commitFunc(&model.Account{})
+		}
+		testError := func(transaction model.Transaction) {
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			So(commitFunc, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+			So(err, shouldBeViolation, violation.ErrorSpendLimitExceeded)
+		}
+
+		Convey("It should NOT authorize a single transaction above the limit", func() {
+			testError(genSpend(0, maxAmount+1))
+		})
+
+		Convey("It should still authorize if the commitFunc is not called", func() {
+			for i := 0; i < 10; i++ {
+				authzer.Authorize(model.Account{}, genSpend(0, maxAmount))
+			}
+			testSuccess(genSpend(0, maxAmount))
+		})
+
+		Convey("When the maximum amount is spent", func() {
+			testSuccess(genSpend(0, 3000))
+			testSuccess(genSpend(1*time.Hour, 2000))
+
+			Convey("It should NOT authorize more spending within the interval", func() {
+				testError(genSpend(interval-1, 1))
+			})
+			Convey("It should authorize spending from another account", func() {
+				tx := genSpend(2*time.Hour, maxAmount)
+				tx.AccountID = "another"
+				testSuccess(tx)
+			})
+			Convey("It should authorize again as the old spending expires", func() {
+				testSuccess(genSpend(interval, 3000))
+				testError(genSpend(interval, 1))
+			})
+		})
+	})
+}
@@ -0,0 +1,31 @@
+package rules
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/model"
+	"nuledger/model/violation"
+)
+
+// SubscriptionAmount is a rule.AuthorizerFunc to guarantee that the recurring
+// charges of the subscriptions registered in the account do not exceed their
+// agreed amounts. It returns a subscription-amount-exceeded violation error if
+// the transaction is a recurring charge of a registered subscription with an
+// amount higher than the subscription maximum.
+//
+// Transactions that are not recurring charges of registered subscriptions are
+// not restricted.
+func SubscriptionAmount(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	subscription, ok := account.SubscriptionOf(transaction)
+	if ok && transaction.Amount > subscription.MaxAmount {
+		return nil, violation.ErrorSubscriptionAmountExceeded
+	}
+	return nil, nil
+}
+
+// isSubscriptionCharge returns whether the transaction is a recurring charge of
+// a subscription registered in the account. Those are agreed upon by the
+// account holder, so they are skipped by the frequency and duplicate rules.
+func isSubscriptionCharge(account model.Account, transaction model.Transaction) bool {
+	_, ok := account.SubscriptionOf(transaction)
+	return ok
+}
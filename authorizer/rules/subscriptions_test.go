@@ -0,0 +1,78 @@
+package rules_test
+
+import (
+	"nuledger/authorizer/rule"
+	"nuledger/authorizer/rules"
+	"nuledger/model"
+	"nuledger/model/violation"
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var subscriptionStartTime = time.Date(2021, time.April, 12, 0, 0, 0, 0, time.UTC)
+
+func TestSubscriptionAmount(t *testing.T) {
+	Convey("Given SubscriptionAmount authorizer function", t, func() {
+		account := model.Account{Subscriptions: []model.Subscription{{ID: "sub", MaxAmount: 30}}}
+		test := func(amount int64, recurring bool) error {
+			transaction := model.Transaction{Merchant: "Streaming Co", Amount: amount, Recurring: recurring, SubscriptionID: "sub"}
+			commitFunc, err := rules.SubscriptionAmount(account, transaction)
+			So(commitFunc, ShouldBeNil)
+			return err
+		}
+
+		Convey("It should authorize recurring charges up to the agreed amount", func() {
+			So(test(30, true), ShouldBeNil)
+		})
+		Convey("It should NOT authorize recurring charges above the agreed amount", func() {
+			So(test(31, true), ShouldResemble, violation.ErrorSubscriptionAmountExceeded)
+		})
+		Convey("It should authorize any transactions that are not recurring", func() {
+			So(test(1000, false), ShouldBeNil)
+		})
+	})
+}
+
+func TestSubscriptionExemptions(t *testing.T) {
+	Convey("Given an account with a subscription", t, func() {
+		account := model.Account{Subscriptions: []model.Subscription{{ID: "sub", MaxAmount: 30}}}
+		charge := model.Transaction{Merchant: "Streaming Co", Amount: 30, Recurring: true, SubscriptionID: "sub", Time: subscriptionStartTime}
+
+		testRule := func(authzer rule.Authorizer) {
+			for i := 0; i < 3; i++ {
+				commitFunc, err := authzer.Authorize(account, charge)
+				So(err, ShouldBeNil)
+				if commitFunc != nil {
+					commitFunc(&account)
+				}
+			}
+
+			Convey("But not other transactions", func() {
+				regular := charge
+				regular.Recurring = false
+				commitFunc, err := authzer.Authorize(account, regular)
+				So(err, ShouldBeNil)
+				commitFunc(&account)
+
+				_, err = authzer.Authorize(account, regular)
+				So(err, ShouldNotBeNil)
+			})
+		}
+
+		Convey("The frequency rules should skip its recurring charges", func() {
+			testRule(rules.NewLimitedFrequency(1, time.Hour))
+		})
+		Convey("The multi-tier frequency rule should skip its recurring charges", func() {
+			testRule(rules.NewMultiTierFrequency(util.RateTier{MaxEvents: 1, Interval: time.Hour}))
+		})
+		Convey("The duplicate rules should skip its recurring charges", func() {
+			testRule(rules.NewUniqueTransactions(rules.UniqueTransactionsConfig{Interval: time.Hour}))
+		})
+		Convey("The fuzzy duplicate rule should skip its recurring charges", func() {
+			testRule(rules.NewFuzzyDuplicates(time.Hour, rules.AmountTolerance{}))
+		})
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"nuledger/authorizer/rule"
 	"nuledger/model"
 	"nuledger/model/violation"
-	"nuledger/util"
 	"time"
 )
 
@@ -14,25 +13,118 @@ type doubleTransactionKey struct {
 	Amount    int64
 }
 
-// NewUniqueTransactions returns a rule.Authorizer to guarantee that no double
+// UniqueTransactionsConfig is the configuration of the NewUniqueTransactions
+// authorizer, with the interval for considering transactions double and the
+// overrides of that interval for some merchants and categories, which may
+// legitimately charge the same amount repeatedly (e.g. transit operators).
+//
+// For each transaction, the exemptions are checked first, then the merchant
+// overrides, then the category ones and finally the default interval is used.
+type UniqueTransactionsConfig struct {
+	// Interval is the default interval for all transactions.
+	Interval time.Duration
+	// MerchantIntervals are the interval overrides for the merchants matching
+	// some pattern. Only the first matching one is used.
+	MerchantIntervals []MerchantInterval
+	// CategoryIntervals are the interval overrides for the transactions of
+	// some merchant categories.
+	CategoryIntervals map[model.Category]time.Duration
+	// ExemptMerchants are the patterns of the merchants whose transactions are
+	// never considered double.
+	ExemptMerchants []string
+	// ExemptCategories are the merchant categories whose transactions are never
+	// considered double.
+	ExemptCategories []model.Category
+}
+
+// MerchantInterval is an override of the double transaction interval for the
+// merchants matching a name pattern, which has the same format as the ones in
+// the account merchant lists (case-insensitive and supporting `*` wildcards).
+type MerchantInterval struct {
+	Merchant string
+	Interval time.Duration
+}
+
+// intervalFor returns the interval to be used for the given transaction, or
+// false if the transaction is exempt.
+func (c UniqueTransactionsConfig) intervalFor(transaction *model.Transaction) (time.Duration, bool) {
+	category := transaction.Category()
+	if matchAnyMerchant(c.ExemptMerchants, transaction.Merchant) || containsCategory(c.ExemptCategories, category) {
+		return 0, false
+	}
+	for _, override := range c.MerchantIntervals {
+		if matchMerchant(override.Merchant, transaction.Merchant) {
+			return override.Interval, true
+		}
+	}
+	if interval, ok := c.CategoryIntervals[category]; ok {
+		return interval, true
+	}
+	return c.Interval, true
+}
+
+// UniqueTransactions is a rule.Authorizer to guarantee that no double
 // (duplicate) transactions are allowed to go through in the account. For 2
 // transactions to be considered double, they must have the exact same merchant
 // and amount, and have timestamps within the configured interval of each other.
 //
-// The `interval` parameter is the minimum amount of time between two
-// transactions with everything else equal for them not to be considered double.
-//
-// Its Authorize function checks if the given transaction is a double,
-// considering the configured interval, and if so the transaction is not
-// authorized and a double-transaction violation error is returned.
-func NewUniqueTransactions(interval time.Duration) rule.Authorizer {
-	limiter := util.RateLimiter{MaxEvents: 1, Interval: interval}
-	keyMapper := func(tx *model.Transaction) interface{} {
-		return doubleTransactionKey{
-			AccountID: tx.AccountID,
-			Merchant:  tx.Merchant,
-			Amount:    tx.Amount,
+// The interval is resolved for each transaction from the config, so that it
+// is compared with the last transaction executed with the same merchant and
+// amount regardless of their categories (e.g. with and without an MCC).
+type UniqueTransactions struct {
+	config UniqueTransactionsConfig
+	last   map[doubleTransactionKey]DuplicateMatch
+}
+
+// NewUniqueTransactions returns a UniqueTransactions authorizer with the given
+// `config`. The interval is the minimum amount of time between two
+// transactions with everything else equal for them not to be considered
+// double. It can be configured for specific merchants and categories through
+// the `config`, which can also exempt them from this rule altogether.
+func NewUniqueTransactions(config UniqueTransactionsConfig) *UniqueTransactions {
+	return &UniqueTransactions{
+		config: config,
+		last:   map[doubleTransactionKey]DuplicateMatch{},
+	}
+}
+
+// Authorize checks if the given transaction is a double, considering its
+// interval, and if so the transaction is not authorized and a
+// double-transaction violation error is returned with FrequencyDetails. The
+// recurring charges of subscriptions registered in the account are never
+// considered double.
+func (u *UniqueTransactions) Authorize(account model.Account, transaction model.Transaction) (rule.CommitFunc, error) {
+	if isSubscriptionCharge(account, transaction) {
+		return nil, nil
+	}
+	interval, ok := u.config.intervalFor(&transaction)
+	if !ok {
+		return nil, nil
+	}
+
+	key := doubleTransactionKey{
+		AccountID: transaction.AccountID,
+		Merchant:  transaction.Merchant,
+		Amount:    transaction.Amount,
+	}
+	if last, ok := u.last[key]; ok {
+		retryAfter := last.Time.Add(interval)
+		if transaction.Time.Before(retryAfter) {
+			details := &FrequencyDetails{RetryAfter: &retryAfter, LastTransaction: last}
+			return nil, violation.ErrorDoubleTransaction.WithDetails(details)
+		}
+	}
+	commit := func(_ *model.Account) {
+		u.last[key] = DuplicateMatch{TransactionID: transaction.ID, Time: transaction.Time}
+	}
+	return commit, nil
+}
+
+func containsCategory(categories []model.Category, category model.Category) bool {
+	for _, elm := range categories {
+		if elm == category {
+			return true
 		}
 	}
-	return NewFrequencyAnalyzer(limiter, keyMapper, violation.ErrorDoubleTransaction)
+	return false
 }
@@ -1,6 +1,7 @@
 package rules_test
 
 import (
+	"errors"
 	"nuledger/authorizer/rules"
 	"nuledger/model"
 	"nuledger/model/violation"
@@ -23,7 +24,7 @@ var (
 func TestUniqueTransactions(t *testing.T) {
 	Convey("Given UniqueTransactions authorizer", t, func() {
 		interval := 1 * time.Minute
-		authzer := rules.NewUniqueTransactions(interval)
+		authzer := rules.NewUniqueTransactions(rules.UniqueTransactionsConfig{Interval: interval})
 
 		Convey("It should authorize initial transactions", func() {
 			commitFunc, err := authzer.Authorize(model.Account{}, baseTransacton)
@@ -34,20 +35,20 @@ func TestUniqueTransactions(t *testing.T) {
 				commitFunc, err := authzer.Authorize(model.Account{}, transaction)
 				So(commitFunc, ShouldNotBeNil)
 				So(err, ShouldBeNil)
-				commitFunc()
+				commitFunc(&model.Account{})
 			}
 
 			Convey("Then it should still authorize an identical transaction if the commitFunc is not called", func() {
 				testSuccess(baseTransacton)
 			})
-			commitFunc()
+			commitFunc(&model.Account{})
 
 			Convey("It should NOT authorize", func() {
 				testError := func(transaction model.Transaction) {
 					commitFunc, err := authzer.Authorize(model.Account{}, transaction)
 					So(commitFunc, ShouldBeNil)
 					So(err, ShouldNotBeNil)
-					So(err, ShouldResemble, violation.ErrorDoubleTransaction)
+					So(err, shouldBeViolation, violation.ErrorDoubleTransaction)
 				}
 
 				Convey("Identical transactions", func() {
@@ -58,6 +59,19 @@ func TestUniqueTransactions(t *testing.T) {
 					repeatedTransaction.Time = uniqueStartTime.Add(interval / 2)
 					testError(repeatedTransaction)
 				})
+				Convey("With the duplicated transaction and retry time in the details", func() {
+					repeatedTransaction := baseTransacton
+					repeatedTransaction.Time = uniqueStartTime.Add(interval / 2)
+					_, err := authzer.Authorize(model.Account{}, repeatedTransaction)
+
+					var verr violation.Error
+					So(errors.As(err, &verr), ShouldBeTrue)
+					retryAfter := uniqueStartTime.Add(interval)
+					So(verr.Details, ShouldResemble, &rules.FrequencyDetails{
+						RetryAfter:      &retryAfter,
+						LastTransaction: rules.DuplicateMatch{Time: uniqueStartTime},
+					})
+				})
 			})
 
 			Convey("And it SHOULD authorize", func() {
@@ -90,3 +104,87 @@ func TestUniqueTransactions(t *testing.T) {
 		})
 	})
 }
+
+func TestUniqueTransactionsConfig(t *testing.T) {
+	Convey("Given UniqueTransactions authorizer with overrides", t, func() {
+		authzer := rules.NewUniqueTransactions(rules.UniqueTransactionsConfig{
+			Interval: 2 * time.Minute,
+			MerchantIntervals: []rules.MerchantInterval{
+				{Merchant: "Metro*", Interval: 10 * time.Second},
+				{Merchant: "*", Interval: 1 * time.Hour},
+			},
+			CategoryIntervals: map[model.Category]time.Duration{model.CategoryGroceries: 5 * time.Minute},
+			ExemptMerchants:   []string{"Bus Line *"},
+			ExemptCategories:  []model.Category{model.CategoryFuel},
+		})
+
+		test := func(merchant, mcc string, diff time.Duration) error {
+			transaction := baseTransacton
+			transaction.Merchant, transaction.MCC = merchant, mcc
+			transaction.Time = uniqueStartTime.Add(diff)
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+
+		Convey("It should never consider exempt merchants double", func() {
+			So(test("Bus Line 8000", "", 0), ShouldBeNil)
+			So(test("BUS LINE 8000", "", 0), ShouldBeNil)
+			So(test("Bus Line 8000", "", 0), ShouldBeNil)
+		})
+		Convey("It should never consider exempt categories double", func() {
+			So(test("Gas Station", "5541", 0), ShouldBeNil)
+			So(test("Gas Station", "5541", 0), ShouldBeNil)
+		})
+		Convey("It should use the first matching merchant interval", func() {
+			So(test("Metro SP", "", 0), ShouldBeNil)
+			So(test("Metro SP", "", 5*time.Second), shouldBeViolation, violation.ErrorDoubleTransaction)
+			So(test("Metro SP", "", 10*time.Second), ShouldBeNil)
+
+			So(test("Bakery", "5411", 0), ShouldBeNil)
+			So(test("Bakery", "5411", 30*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+		})
+		Convey("It should apply the exemptions of each transaction", func() {
+			So(test("Corner Shop", "5411", 0), ShouldBeNil)
+			So(test("Corner Shop", "5541", time.Minute), ShouldBeNil)
+			So(test("Corner Shop", "5541", time.Minute), ShouldBeNil)
+			So(test("Corner Shop", "", 2*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+		})
+	})
+
+	Convey("Given UniqueTransactions authorizer with category overrides", t, func() {
+		authzer := rules.NewUniqueTransactions(rules.UniqueTransactionsConfig{
+			Interval:          2 * time.Minute,
+			CategoryIntervals: map[model.Category]time.Duration{model.CategoryGroceries: 5 * time.Minute},
+		})
+		test := func(mcc string, diff time.Duration) error {
+			transaction := baseTransacton
+			transaction.MCC = mcc
+			transaction.Time = uniqueStartTime.Add(diff)
+			commitFunc, err := authzer.Authorize(model.Account{}, transaction)
+			if commitFunc != nil {
+				commitFunc(&model.Account{})
+			}
+			return err
+		}
+
+		Convey("It should use the category interval", func() {
+			So(test("5411", 0), ShouldBeNil)
+			So(test("5411", 3*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+			So(test("5411", 5*time.Minute), ShouldBeNil)
+		})
+		Convey("It should use the default interval for other categories", func() {
+			So(test("5812", 0), ShouldBeNil)
+			So(test("5812", 1*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+			So(test("5812", 2*time.Minute), ShouldBeNil)
+		})
+		Convey("It should use the interval of each transaction regardless of the previous one category", func() {
+			So(test("5812", 0), ShouldBeNil)
+			So(test("5411", 3*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+			So(test("5812", 3*time.Minute), ShouldBeNil)
+			So(test("", 4*time.Minute), shouldBeViolation, violation.ErrorDoubleTransaction)
+		})
+	})
+}
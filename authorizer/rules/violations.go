@@ -0,0 +1,49 @@
+package rules
+
+import "nuledger/model/violation"
+
+// init registers the violation codes returned by the rules of this package.
+func init() {
+	violation.MustRegister(
+		definition(violation.ErrorCardNotActive, violation.SeverityHigh, false),
+		definition(violation.ErrorInsufficientLimit, violation.SeverityMedium, false),
+		definition(violation.ErrorHighFrequencySmallInterval, violation.SeverityLow, true),
+		definition(violation.ErrorDoubleTransaction, violation.SeverityLow, true),
+		definition(violation.ErrorOverdraftLimitExceeded, violation.SeverityMedium, false),
+		definition(violation.ErrorSpendLimitExceeded, violation.SeverityMedium, true),
+		definition(violation.ErrorCategoryBlocked, violation.SeverityMedium, false),
+		definition(violation.ErrorCategoryLimitExceeded, violation.SeverityMedium, true),
+		definition(violation.ErrorMerchantNotAllowed, violation.SeverityMedium, false),
+		definition(violation.ErrorCountryNotAllowed, violation.SeverityMedium, false),
+		definition(violation.ErrorOutsideAllowedSchedule, violation.SeverityLow, true),
+		definition(violation.ErrorCardTestingSuspected, violation.SeverityHigh, false),
+		definition(violation.ErrorSubscriptionAmountExceeded, violation.SeverityMedium, false),
+		violation.Definition{
+			Code:        violation.MerchantQuarantined,
+			Severity:    violation.SeverityHigh,
+			Description: "Merchant is quarantined after a burst of transactions across the accounts",
+			Retriable:   true,
+		},
+		violation.Definition{
+			Code:        violation.ImpossibleTravel,
+			Severity:    violation.SeverityHigh,
+			Description: "Transaction location is too far from the previous one for the time between them",
+		},
+		violation.Definition{
+			Code:        violation.HighRisk,
+			Severity:    violation.SeverityHigh,
+			Description: "Transaction risk score is above the threshold",
+		},
+		violation.Definition{
+			Code:        violation.AmountAnomaly,
+			Severity:    violation.SeverityMedium,
+			Description: "Transaction amount is anomalous compared to the account history",
+		},
+	)
+}
+
+// definition returns the definition of the code of a violation error, described
+// by its message.
+func definition(err violation.Error, severity violation.Severity, retriable bool) violation.Definition {
+	return violation.Definition{Code: err.Code, Severity: severity, Description: err.Message, Retriable: retriable}
+}
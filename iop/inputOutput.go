@@ -1,13 +1,14 @@
 package iop
 
 import (
+	"encoding/json"
 	"nuledger/model"
 	"nuledger/model/violation"
 )
 
 // OperationInput is a JSON received as an input for an operation to be run. It
-// can have only one of Account or Transaction fields set, each one representing
-// a different kind of operation being requested.
+// can have only one of its fields set, each one representing a different kind
+// of operation being requested.
 type OperationInput struct {
 	// Account represents an account creation request. If it is not null, it
 	// should contain the initial state of the account to be created.
@@ -15,6 +16,22 @@ type OperationInput struct {
 	// Transaction represents a transaction request. If it is not null, it
 	// should contain the details about the transaction being attempted.
 	Transaction *model.Transaction `json:"transaction"`
+	// CategoryControls represents a request to update the category controls of
+	// an account. If it is not null, it should contain the new controls.
+	CategoryControls *model.CategoryControlsUpdate `json:"category-controls,omitempty"`
+	// MerchantAllowlist represents a request to replace the merchant allowlist
+	// of an account. If it is not null, it should contain the new patterns.
+	MerchantAllowlist *model.MerchantListUpdate `json:"merchant-allowlist,omitempty"`
+	// MerchantBlocklist represents a request to replace the merchant blocklist
+	// of an account. If it is not null, it should contain the new patterns.
+	MerchantBlocklist *model.MerchantListUpdate `json:"merchant-blocklist,omitempty"`
+	// Subscriptions represents a request to replace the registered
+	// subscriptions of an account. If it is not null, it should contain the
+	// new subscriptions.
+	Subscriptions *model.SubscriptionsUpdate `json:"subscriptions,omitempty"`
+	// Profile represents a request to switch the profile of an account. If it
+	// is not null, it should contain the name of the new profile.
+	Profile *model.ProfileUpdate `json:"profile,omitempty"`
 }
 
 // StateOutput represents a JSON to be written in the output as the result of
@@ -28,4 +45,41 @@ type StateOutput struct {
 	// Violations represent any violation that may have prevented the operation
 	// from being performed. It will be an empty array in case of a success.
 	Violations []violation.Code `json:"violations"`
+	// ViolationDetails are the same violations as objects with their messages
+	// and metadata, only set in the structured violations output mode. If not
+	// nil, they are written as the violations instead of the plain codes.
+	ViolationDetails []ViolationOutput `json:"-"`
+	// ShadowViolations are the violations of the rules running in shadow mode,
+	// which didn't prevent the operation from succeeding but are reported for
+	// evaluating the rules.
+	ShadowViolations []violation.Code `json:"shadow-violations,omitempty"`
+	// Risk is the risk assessment of a transaction authorized with the risk
+	// score rule, whether declined or not, with the score and the signals that
+	// contributed to it. It is omitted for any other operations.
+	Risk *model.RiskAssessment `json:"risk,omitempty"`
+	// Trace is the decision of each rule that authorized a transaction, only
+	// included in the verbose output mode.
+	Trace []model.RuleDecision `json:"trace,omitempty"`
+}
+
+// ViolationOutput represents a violation in the structured violations output
+// mode, with a human-readable message and any metadata specific to the rule
+// that returned it (e.g. how much of a limit remains).
+type ViolationOutput struct {
+	Code     violation.Code `json:"code"`
+	Message  string         `json:"message"`
+	Metadata interface{}    `json:"metadata,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, writing the
+// ViolationDetails as the violations if they are set.
+func (o StateOutput) MarshalJSON() ([]byte, error) {
+	type plainOutput StateOutput
+	if o.ViolationDetails == nil {
+		return json.Marshal(plainOutput(o))
+	}
+	return json.Marshal(struct {
+		plainOutput
+		Violations []ViolationOutput `json:"violations"`
+	}{plainOutput(o), o.ViolationDetails})
 }
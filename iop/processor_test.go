@@ -92,11 +92,11 @@ func TestIOProcessor(t *testing.T) {
 
 		Convey("When objects are read from input and returned by handler", func() {
 			input := iop.OperationInput{
-				Account:     &model.Account{"", true, 1337},
-				Transaction: &model.Transaction{"", "sketchy", 420, startTime},
+				Account:     &model.Account{ActiveCard: true, AvailableLimit: 1337},
+				Transaction: &model.Transaction{Merchant: "sketchy", Amount: 420, Time: startTime},
 			}
 			expected := iop.StateOutput{
-				Account:    &model.Account{"", false, 7331},
+				Account:    &model.Account{ActiveCard: false, AvailableLimit: 7331},
 				Violations: []violation.Code{"not-even-a-violation"},
 			}
 
@@ -111,13 +111,31 @@ func TestIOProcessor(t *testing.T) {
 			})
 		})
 
+		Convey("When the handler returns structured violations", func() {
+			input := iop.OperationInput{Transaction: &model.Transaction{Amount: 23}}
+			handler.EXPECT().
+				Handle(gomock.Eq(input)).
+				Return(iop.StateOutput{
+					Violations: []violation.Code{"not-even-a-violation"},
+					ViolationDetails: []iop.ViolationOutput{
+						{Code: "not-even-a-violation", Message: "Hello violations", Metadata: map[string]int{"answer": 42}},
+					},
+				}, nil)
+
+			Convey("It should write them as objects in place of the codes", func() {
+				writeInputs(in, input)
+				So(processor.Process(), ShouldBeNil)
+				So(out.String(), ShouldEqual, `{"account":null,"violations":[{"code":"not-even-a-violation","message":"Hello violations","metadata":{"answer":42}}]}`+"\n")
+			})
+		})
+
 		Convey("When multiple objects are read from input", func() {
 			input := []iop.OperationInput{
 				{Account: &model.Account{AvailableLimit: 42}},
 				{Transaction: &model.Transaction{Amount: 23}},
 			}
 			expected := []iop.StateOutput{
-				{Account: &model.Account{"", true, 13}},
+				{Account: &model.Account{ActiveCard: true, AvailableLimit: 13}},
 				{Violations: []violation.Code{"surely-another-non-violation"}},
 			}
 
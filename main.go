@@ -1,21 +1,113 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"io"
 	"os"
+	_ "time/tzdata" // embed time zones used by accounts, as containers may lack them
 
 	"nuledger/authorizer"
+	"nuledger/authorizer/config"
+	"nuledger/authorizer/rule"
 	"nuledger/iop"
+	"nuledger/model/violation"
 )
 
 var (
 	stdin  io.Reader = os.Stdin
 	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+
+	configPath    = flag.String("config", "", "path to a JSON file configuring the authorization rules (uses the default rules if empty)")
+	verbose       = flag.Bool("verbose", false, "include the decision of each rule in the output of transactions")
+	reportMetrics = flag.Bool("metrics", false, "write the metrics of the rules in shadow mode and rollouts to stderr after processing the input")
+	structured    = flag.Bool("structured-violations", false, "write the violations as objects with their messages and metadata instead of only their codes")
+	listCodes     = flag.Bool("list-violations", false, "write the registered violation codes (including the configured ones) to stdout instead of processing the input")
 )
 
 func main() {
-	processor := iop.NewProcessor(stdin, stdout, authorizer.NewHandler())
+	flag.Parse()
+	handler, authzers, err := newHandler(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	if *listCodes {
+		if err := writeViolations(stdout, violation.Registered()); err != nil {
+			panic(err)
+		}
+		return
+	}
+	handler.Verbose = *verbose
+	handler.StructuredViolations = *structured
+
+	processor := iop.NewProcessor(stdin, stdout, handler)
 	if err := processor.Process(); err != nil {
 		panic(err)
 	}
+
+	if *reportMetrics {
+		if err := writeMetrics(stderr, rule.CollectMetrics(authzers...)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// newHandler creates the handler with the rules and profiles configured in the
+// given file, or with the default rules if no file is given. It also returns
+// all the configured authorizers, for collecting their metrics.
+func newHandler(configPath string) (*authorizer.Handler, []rule.Authorizer, error) {
+	if configPath == "" {
+		authzer := authorizer.DefaultAuthorizer()
+		return &authorizer.Handler{Ledger: authorizer.NewLedger(authzer)}, []rule.Authorizer{authzer}, nil
+	}
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	authzer, err := cfg.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	profiles, err := cfg.BuildProfiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authzers := []rule.Authorizer{authzer}
+	for _, profile := range cfg.ProfileNames() {
+		authzers = append(authzers, profiles[profile])
+	}
+	return &authorizer.Handler{Ledger: authorizer.NewProfileLedger(authzer, profiles)}, authzers, nil
+}
+
+// metricsReport is the report of the metrics of a rule, including the decline
+// rate for comparing the rules (or the cohorts of a rollout).
+type metricsReport struct {
+	rule.Metrics
+	DeclineRate float64 `json:"decline-rate"`
+}
+
+// writeMetrics writes the report of each of the rule metrics as a JSON line.
+func writeMetrics(w io.Writer, metrics []rule.Metrics) error {
+	encoder := json.NewEncoder(w)
+	for _, ruleMetrics := range metrics {
+		report := metricsReport{Metrics: ruleMetrics, DeclineRate: ruleMetrics.DeclineRate()}
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeViolations writes the definition of each of the violation codes as a
+// JSON line.
+func writeViolations(w io.Writer, defs []violation.Definition) error {
+	encoder := json.NewEncoder(w)
+	for _, def := range defs {
+		if err := encoder.Encode(def); err != nil {
+			return err
+		}
+	}
+	return nil
 }
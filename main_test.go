@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -16,6 +18,7 @@ const (
 	baseTestCasesDir = "./testcases"
 	inputFileName    = "in.jsonl"
 	outputFileName   = "out.jsonl"
+	configFileName   = "config.json"
 )
 
 func TestInputOutputCases(t *testing.T) {
@@ -26,11 +29,103 @@ func TestInputOutputCases(t *testing.T) {
 			input, output := bytes.NewReader([]byte(`not a json`)), bytes.NewBuffer(nil)
 			So(func() { testMain(input, output) }, ShouldPanic)
 		})
+		Convey("Panics in case of an invalid config", func() {
+			prevConfig := *configPath
+			defer func() { *configPath = prevConfig }()
+
+			*configPath = path.Join(baseTestCasesDir, "does-not-exist.json")
+			So(func() { testMain(bytes.NewReader(nil), bytes.NewBuffer(nil)) }, ShouldPanic)
+		})
+
+		Convey("Writes the metrics of the shadow rules if requested", func() {
+			prevConfig, prevMetrics, prevErr := *configPath, *reportMetrics, stderr
+			defer func() { *configPath, *reportMetrics, stderr = prevConfig, prevMetrics, prevErr }()
+
+			errBuf := bytes.NewBuffer(nil)
+			*configPath, *reportMetrics, stderr = getTestCaseConfig("shadowRules"), true, errBuf
+			input, _ := getTestCase("shadowRules")
+			testMain(input, bytes.NewBuffer(nil))
+
+			expected := `{"rule":"stricter-frequency","evaluated":5,"declined":3,"violations":{"high-frequency-small-interval":3},"decline-rate":0.6}`
+			So(readLines(errBuf), ShouldResemble, []string{expected})
+		})
+
+		Convey("Lists the registered violation codes if requested", func() {
+			prevConfig, prevList := *configPath, *listCodes
+			defer func() { *configPath, *listCodes = prevConfig, prevList }()
+
+			*configPath, *listCodes = getTestCaseConfig("expressionRules"), true
+			outputBuf := bytes.NewBuffer(nil)
+			testMain(bytes.NewReader([]byte(`not a json`)), outputBuf)
+
+			output := readLines(outputBuf)
+			So(output, ShouldContain, `{"code":"insufficient-limit","severity":"medium","description":"Transaction amount is higher than available limit","retriable":false}`)
+			So(output, ShouldContain, `{"code":"double-transaction","severity":"low","description":"Duplicate transaction of same amount and merchant","retriable":true}`)
+			So(strings.Join(output, "\n"), ShouldContainSubstring, `{"code":"gambling-limit-exceeded","severity":"medium"`)
+		})
+
+		Convey("Includes the decision of each rule in verbose mode", func() {
+			prevVerbose := *verbose
+			defer func() { *verbose = prevVerbose }()
+
+			*verbose = true
+			input := bytes.NewReader([]byte(`{"account": {"active-card": false, "available-limit": 100}}
+				{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`))
+			outputBuf := bytes.NewBuffer(nil)
+			testMain(input, outputBuf)
+
+			output := readLines(outputBuf)
+			So(output, ShouldHaveLength, 2)
+			So(output[0], ShouldNotContainSubstring, `"trace"`)
+			So(output[1], ShouldContainSubstring, `{"rule":"account-card-active","outcome":"fail","violations":["card-not-active"],"message":"Account card is not active"`)
+			So(output[1], ShouldContainSubstring, `{"rule":"sufficient-limit","outcome":"pass"`)
+		})
+
+		Convey("Writes the violations as objects in structured mode", func() {
+			prevStructured := *structured
+			defer func() { *structured = prevStructured }()
+
+			*structured = true
+			input := bytes.NewReader([]byte(`{"account": {"active-card": true, "available-limit": 100}}
+				{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+				{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}
+				{"transaction": {"merchant": "Habbib's", "amount": 90, "time": "2019-02-13T10:01:00.000Z"}}`))
+			outputBuf := bytes.NewBuffer(nil)
+			testMain(input, outputBuf)
+
+			output := readLines(outputBuf)
+			So(output, ShouldHaveLength, 4)
+			So(output[1], ShouldContainSubstring, `"violations":[]`)
+			So(output[2], ShouldContainSubstring, `"violations":[{"code":"double-transaction","message":`)
+			So(output[2], ShouldContainSubstring, `"metadata":{"retry-after":"2019-02-13T10:02:00Z","last-transaction":{"time":"2019-02-13T10:00:00Z"}}`)
+			So(output[3], ShouldContainSubstring, `"violations":[{"code":"insufficient-limit","message":`)
+			So(output[3], ShouldContainSubstring, `"metadata":{"remaining":80}`)
+		})
+
+		Convey("Writes the localized messages of the violations in structured mode", func() {
+			prevStructured := *structured
+			defer func() { *structured = prevStructured }()
+
+			*structured = true
+			input := bytes.NewReader([]byte(`{"account": {"active-card": true, "available-limit": 100, "locale": "pt-BR"}}
+				{"transaction": {"merchant": "Burger King", "amount": 120, "time": "2019-02-13T10:00:00.000Z"}}`))
+			outputBuf := bytes.NewBuffer(nil)
+			testMain(input, outputBuf)
+
+			output := readLines(outputBuf)
+			So(output, ShouldHaveLength, 2)
+			So(output[1], ShouldContainSubstring,
+				`"violations":[{"code":"insufficient-limit","message":"O valor da transação é maior que o limite disponível de 100","metadata":{"remaining":100}}]`)
+		})
 
 		for _, caseName := range cases {
 			Convey(fmt.Sprintf(`Correctly handles test case "%s"`, caseName), func() {
 				input, expectedBuf := getTestCase(caseName)
 
+				prevConfig := *configPath
+				defer func() { *configPath = prevConfig }()
+				*configPath = getTestCaseConfig(caseName)
+
 				outputBuf := bytes.NewBuffer(nil)
 				testMain(input, outputBuf)
 
@@ -75,6 +170,16 @@ func getTestCase(caseName string) (input io.Reader, expectedOutput io.Reader) {
 	return readFile(inputFile), readFile(outputFile)
 }
 
+// getTestCaseConfig returns the path to the rules configuration of the test
+// case, or an empty string if it uses the default rules.
+func getTestCaseConfig(caseName string) string {
+	configFile := path.Join(baseTestCasesDir, caseName, configFileName)
+	if _, err := os.Stat(configFile); err != nil {
+		return ""
+	}
+	return configFile
+}
+
 func readFile(path string) io.Reader {
 	content, err := ioutil.ReadFile(path)
 	So(err, ShouldBeNil)
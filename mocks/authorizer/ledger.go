@@ -63,3 +63,108 @@ func (mr *MockLedgerMockRecorder) PerformTransaction(transaction interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PerformTransaction", reflect.TypeOf((*MockLedger)(nil).PerformTransaction), transaction)
 }
+
+// RiskAssessment mocks base method.
+func (m *MockLedger) RiskAssessment(accountID string) *model.RiskAssessment {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RiskAssessment", accountID)
+	ret0, _ := ret[0].(*model.RiskAssessment)
+	return ret0
+}
+
+// RiskAssessment indicates an expected call of RiskAssessment.
+func (mr *MockLedgerMockRecorder) RiskAssessment(accountID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RiskAssessment", reflect.TypeOf((*MockLedger)(nil).RiskAssessment), accountID)
+}
+
+// TraceTransaction mocks base method.
+func (m *MockLedger) TraceTransaction(transaction model.Transaction) (*model.Account, []model.RuleDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TraceTransaction", transaction)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].([]model.RuleDecision)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TraceTransaction indicates an expected call of TraceTransaction.
+func (mr *MockLedgerMockRecorder) TraceTransaction(transaction interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TraceTransaction", reflect.TypeOf((*MockLedger)(nil).TraceTransaction), transaction)
+}
+
+// UpdateCategoryControls mocks base method.
+func (m *MockLedger) UpdateCategoryControls(update model.CategoryControlsUpdate) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCategoryControls", update)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCategoryControls indicates an expected call of UpdateCategoryControls.
+func (mr *MockLedgerMockRecorder) UpdateCategoryControls(update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCategoryControls", reflect.TypeOf((*MockLedger)(nil).UpdateCategoryControls), update)
+}
+
+// UpdateMerchantAllowlist mocks base method.
+func (m *MockLedger) UpdateMerchantAllowlist(update model.MerchantListUpdate) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMerchantAllowlist", update)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMerchantAllowlist indicates an expected call of UpdateMerchantAllowlist.
+func (mr *MockLedgerMockRecorder) UpdateMerchantAllowlist(update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMerchantAllowlist", reflect.TypeOf((*MockLedger)(nil).UpdateMerchantAllowlist), update)
+}
+
+// UpdateMerchantBlocklist mocks base method.
+func (m *MockLedger) UpdateMerchantBlocklist(update model.MerchantListUpdate) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMerchantBlocklist", update)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMerchantBlocklist indicates an expected call of UpdateMerchantBlocklist.
+func (mr *MockLedgerMockRecorder) UpdateMerchantBlocklist(update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMerchantBlocklist", reflect.TypeOf((*MockLedger)(nil).UpdateMerchantBlocklist), update)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockLedger) UpdateProfile(update model.ProfileUpdate) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", update)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockLedgerMockRecorder) UpdateProfile(update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockLedger)(nil).UpdateProfile), update)
+}
+
+// UpdateSubscriptions mocks base method.
+func (m *MockLedger) UpdateSubscriptions(update model.SubscriptionsUpdate) (*model.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscriptions", update)
+	ret0, _ := ret[0].(*model.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSubscriptions indicates an expected call of UpdateSubscriptions.
+func (mr *MockLedgerMockRecorder) UpdateSubscriptions(update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscriptions", reflect.TypeOf((*MockLedger)(nil).UpdateSubscriptions), update)
+}
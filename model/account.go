@@ -1,21 +1,129 @@
 // Package model contains all the model types shared by the whole application.
 package model
 
+import (
+	"nuledger/model/violation"
+	"sync"
+	"time"
+)
+
+// locations caches the time zones already loaded by their names, since loading
+// them reads the time zone database every time.
+var locations sync.Map
+
 // Account represents both the current account state sent on response messages
 // as well as the account creation object representing its initial state.
 type Account struct {
 	// ID is a unique identifier for the respective account.
 	ID string `json:"id,omitempty"`
+	// Profile is the name of the product profile of the account (e.g. "basic"
+	// or "premium"), which selects the set of rules used to authorize its
+	// transactions. If empty, the default rules are used.
+	Profile string `json:"profile,omitempty"`
 	// ActiveCard represents if the account card is active or not. An inactive
 	// card does not authorize any transactions.
 	ActiveCard bool `json:"active-card"`
 	// AvailableLimit is the units of currency that the account still has.
-	// Transactions consume from this limit and it can never be exceeded.
+	// Transactions consume from this limit and it can never be exceeded, unless
+	// the account has some overdraft allowance and the overdraft rule is used.
 	AvailableLimit int64 `json:"available-limit"`
+	// OverdraftLimit is the units of currency that the available limit is
+	// allowed to go below zero when authorizing with an overdraft-capable rule.
+	// Zero means the account has no overdraft allowance.
+	OverdraftLimit int64 `json:"overdraft-limit,omitempty"`
+	// TimeZone is the IANA name of the time zone of the account holder (e.g.
+	// "America/Sao_Paulo"), used by rules that depend on the local calendar
+	// like limits per calendar day. It defaults to UTC if left empty.
+	TimeZone string `json:"time-zone,omitempty"`
+	// Locale is the language of the account holder (e.g. "pt" or "es-AR"),
+	// used to localize the messages of the violations in the structured output.
+	// If empty, the messages are the ones of the rules themselves.
+	Locale violation.Locale `json:"locale,omitempty"`
+	// CategoryControls are the controls applied to the transactions of each
+	// merchant category in the account, like blocking them or limiting the
+	// amount spent on them.
+	CategoryControls map[Category]CategoryControl `json:"category-controls,omitempty"`
+	// MerchantAllowlist are the patterns of the merchant names where the card
+	// of the account can be used. If empty, any merchant is allowed.
+	MerchantAllowlist []string `json:"merchant-allowlist,omitempty"`
+	// MerchantBlocklist are the patterns of the merchant names where the card
+	// of the account can never be used, even if they are in the allowlist.
+	MerchantBlocklist []string `json:"merchant-blocklist,omitempty"`
+	// HomeCountry is the code of the country of the account holder, where
+	// transactions are not considered foreign by the risk analysis.
+	HomeCountry string `json:"home-country,omitempty"`
+	// AllowedCountries are the codes of the countries where the card of the
+	// account can be used. If empty, any country is allowed.
+	AllowedCountries []string `json:"allowed-countries,omitempty"`
+	// BlockedCountries are the codes of the countries where the card of the
+	// account can never be used.
+	BlockedCountries []string `json:"blocked-countries,omitempty"`
+	// Schedule restricts the times of the week when the card of the account
+	// can be used, in the account's time zone. If nil, there are no such
+	// restrictions.
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// Subscriptions are the recurring agreements registered in the account,
+	// whose recurring transactions are not subject to the velocity rules but
+	// can't exceed the agreed amounts.
+	Subscriptions []Subscription `json:"subscriptions,omitempty"`
+}
+
+// Validate checks the configurations of the account that could be invalid,
+// like its time zone and schedule, returning an error if any of them is.
+func (a Account) Validate() error {
+	if _, err := a.Location(); err != nil {
+		return err
+	}
+	if a.Schedule != nil {
+		return a.Schedule.Validate()
+	}
+	return nil
+}
+
+// Location returns the time zone configured for the account as a location that
+// can be used to convert any times to the account's local time. It returns an
+// error if the TimeZone is not a valid time zone name. Each time zone is only
+// loaded once, being cached for all the accounts.
+func (a Account) Location() (*time.Location, error) {
+	if a.TimeZone == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(a.TimeZone); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(a.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(a.TimeZone, loc)
+	return loc, nil
 }
 
 // Copy is a helper function for creating a copy of the current object and
-// returning it as a pointer.
+// returning it as a pointer. The copy doesn't share any of its configurations
+// with the original account, so either of them can be modified independently.
 func (a Account) Copy() *Account {
+	if a.CategoryControls != nil {
+		controls := make(map[Category]CategoryControl, len(a.CategoryControls))
+		for category, control := range a.CategoryControls {
+			controls[category] = control
+		}
+		a.CategoryControls = controls
+	}
+	a.MerchantAllowlist = copyStrings(a.MerchantAllowlist)
+	a.MerchantBlocklist = copyStrings(a.MerchantBlocklist)
+	a.AllowedCountries = copyStrings(a.AllowedCountries)
+	a.BlockedCountries = copyStrings(a.BlockedCountries)
+	a.Schedule = a.Schedule.copy()
+	if a.Subscriptions != nil {
+		a.Subscriptions = append(make([]Subscription, 0, len(a.Subscriptions)), a.Subscriptions...)
+	}
 	return &a
 }
+
+func copyStrings(slc []string) []string {
+	if slc == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(slc)), slc...)
+}
@@ -3,6 +3,7 @@ package model_test
 import (
 	"nuledger/model"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -19,5 +20,98 @@ func TestAccountCopy(t *testing.T) {
 				So(*copy, ShouldResemble, *account)
 			})
 		})
+
+		Convey("Copy does not share the category controls", func() {
+			account.CategoryControls = map[model.Category]model.CategoryControl{
+				model.CategoryGambling: {Blocked: true},
+			}
+			copy := account.Copy()
+			So(copy.CategoryControls, ShouldResemble, account.CategoryControls)
+
+			copy.CategoryControls[model.CategoryFuel] = model.CategoryControl{MaxAmount: 200}
+			So(account.CategoryControls, ShouldHaveLength, 1)
+		})
+
+		Convey("Copy does not share the merchant lists", func() {
+			account.MerchantAllowlist = []string{"Uber*"}
+			account.MerchantBlocklist = []string{"Uber Eats"}
+			copy := account.Copy()
+			So(copy.MerchantAllowlist, ShouldResemble, account.MerchantAllowlist)
+			So(copy.MerchantBlocklist, ShouldResemble, account.MerchantBlocklist)
+
+			copy.MerchantAllowlist[0] = "Lyft"
+			So(account.MerchantAllowlist[0], ShouldEqual, "Uber*")
+		})
+
+		Convey("Copy does not share the country lists", func() {
+			account.AllowedCountries = []string{"BR", "PT"}
+			account.BlockedCountries = []string{"KP"}
+			copy := account.Copy()
+			So(copy.AllowedCountries, ShouldResemble, account.AllowedCountries)
+			So(copy.BlockedCountries, ShouldResemble, account.BlockedCountries)
+
+			copy.BlockedCountries[0] = "US"
+			So(account.BlockedCountries[0], ShouldEqual, "KP")
+		})
+
+		Convey("Copy does not share the schedule", func() {
+			account.Schedule = &model.Schedule{Windows: []model.ScheduleWindow{{Weekdays: []string{"monday"}, From: 9, To: 18}}}
+			copy := account.Copy()
+			So(copy.Schedule, ShouldResemble, account.Schedule)
+			So(copy.Schedule, ShouldNotPointTo, account.Schedule)
+
+			copy.Schedule.Windows[0].Weekdays[0] = "sunday"
+			So(account.Schedule.Windows[0].Weekdays[0], ShouldEqual, "monday")
+		})
+
+		Convey("Copy does not share the subscriptions", func() {
+			account.Subscriptions = []model.Subscription{{ID: "sub", MaxAmount: 10}}
+			copy := account.Copy()
+			So(copy.Subscriptions, ShouldResemble, account.Subscriptions)
+
+			copy.Subscriptions[0].MaxAmount = 20
+			So(account.Subscriptions[0].MaxAmount, ShouldEqual, 10)
+		})
+	})
+}
+
+func TestAccountLocation(t *testing.T) {
+	Convey("Given an account object", t, func() {
+		account := model.Account{}
+
+		Convey("It should default to UTC location", func() {
+			loc, err := account.Location()
+			So(err, ShouldBeNil)
+			So(loc, ShouldEqual, time.UTC)
+		})
+
+		Convey("It should load the configured time zone", func() {
+			account.TimeZone = "America/Sao_Paulo"
+			loc, err := account.Location()
+			So(err, ShouldBeNil)
+			So(loc.String(), ShouldEqual, account.TimeZone)
+		})
+
+		Convey("It should load each time zone only once", func() {
+			account.TimeZone = "Asia/Tokyo"
+			loc, err := account.Location()
+			So(err, ShouldBeNil)
+			other, err := model.Account{TimeZone: "Asia/Tokyo"}.Location()
+			So(err, ShouldBeNil)
+			So(other, ShouldPointTo, loc)
+		})
+
+		Convey("It should return an error for an invalid time zone", func() {
+			account.TimeZone = "Nowhere/Atlantis"
+			_, err := account.Location()
+			So(err, ShouldNotBeNil)
+			So(account.Validate(), ShouldNotBeNil)
+		})
+
+		Convey("It should validate its schedule", func() {
+			So(account.Validate(), ShouldBeNil)
+			account.Schedule = &model.Schedule{Windows: []model.ScheduleWindow{{From: 18, To: 9}}}
+			So(account.Validate(), ShouldNotBeNil)
+		})
 	})
 }
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"nuledger/util"
+	"strconv"
+)
+
+// Category is an enum to represent the merchant categories of the taxonomy
+// used by the application, each one grouping multiple merchant category codes
+// (MCCs) of the same kind of business.
+type Category string
+
+const (
+	CategoryUncategorized  Category = ""
+	CategoryOther          Category = "other"
+	CategoryTravel         Category = "travel"
+	CategoryTransportation Category = "transportation"
+	CategoryFuel           Category = "fuel"
+	CategoryGroceries      Category = "groceries"
+	CategoryRestaurants    Category = "restaurants"
+	CategoryCash           Category = "cash"
+	CategoryGambling       Category = "gambling"
+)
+
+// mccRange is an inclusive range of merchant category codes belonging to the
+// same category.
+type mccRange struct {
+	from, to int
+	category Category
+}
+
+// mccRanges is the taxonomy mapping the merchant category codes to categories.
+var mccRanges = []mccRange{
+	{3000, 3299, CategoryTravel}, // airlines
+	{3351, 3441, CategoryTravel}, // car rentals
+	{3501, 3999, CategoryTravel}, // lodging
+	{4111, 4131, CategoryTransportation},
+	{4511, 4511, CategoryTravel},
+	{4784, 4784, CategoryTransportation}, // tolls
+	{5411, 5411, CategoryGroceries},
+	{5422, 5499, CategoryGroceries},
+	{5541, 5542, CategoryFuel},
+	{5811, 5814, CategoryRestaurants},
+	{5983, 5983, CategoryFuel},
+	{6010, 6011, CategoryCash},
+	{7011, 7011, CategoryTravel},
+	{7512, 7512, CategoryTravel},
+	{7800, 7802, CategoryGambling},
+	{7995, 7995, CategoryGambling},
+}
+
+// CategoryOf returns the category of the given merchant category code. It
+// returns CategoryUncategorized for an empty or invalid code and CategoryOther
+// for any valid code not specifically mapped by the taxonomy.
+func CategoryOf(mcc string) Category {
+	code, err := strconv.Atoi(mcc)
+	if err != nil || len(mcc) != 4 {
+		return CategoryUncategorized
+	}
+	for _, r := range mccRanges {
+		if code >= r.from && code <= r.to {
+			return r.category
+		}
+	}
+	return CategoryOther
+}
+
+// CategoryControl is the configuration of the controls applied to transactions
+// of a specific category in an account.
+type CategoryControl struct {
+	// Blocked represents whether transactions of the category are completely
+	// blocked in the account.
+	Blocked bool `json:"blocked,omitempty"`
+	// MaxAmount is the maximum amount that can be spent in transactions of the
+	// category within the calendar window below. Zero means no limit.
+	MaxAmount int64 `json:"max-amount,omitempty"`
+	// Window is the calendar window in which the MaxAmount is limited. It
+	// defaults to a calendar day.
+	Window util.CalendarWindow `json:"window,omitempty"`
+}
+
+// CategoryControlsUpdate is a request for replacing the category controls of an
+// existing account.
+type CategoryControlsUpdate struct {
+	// AccountID is the unique identifier of the account to be updated.
+	AccountID string `json:"accountId"`
+	// Controls are the new controls of the account, replacing any previous
+	// ones. Categories absent from it have no controls at all.
+	Controls map[Category]CategoryControl `json:"controls"`
+}
@@ -0,0 +1,30 @@
+package model_test
+
+import (
+	"nuledger/model"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCategoryOf(t *testing.T) {
+	Convey("Given CategoryOf function", t, func() {
+		Convey("It should map codes to their categories", func() {
+			So(model.CategoryOf("5542"), ShouldEqual, model.CategoryFuel)
+			So(model.CategoryOf("7995"), ShouldEqual, model.CategoryGambling)
+			So(model.CategoryOf("3005"), ShouldEqual, model.CategoryTravel)
+			So(model.CategoryOf("5812"), ShouldEqual, model.CategoryRestaurants)
+		})
+		Convey("It should return other for unmapped codes", func() {
+			So(model.CategoryOf("1520"), ShouldEqual, model.CategoryOther)
+		})
+		Convey("It should return uncategorized for empty or invalid codes", func() {
+			So(model.CategoryOf(""), ShouldEqual, model.CategoryUncategorized)
+			So(model.CategoryOf("542"), ShouldEqual, model.CategoryUncategorized)
+			So(model.CategoryOf("fuel"), ShouldEqual, model.CategoryUncategorized)
+		})
+		Convey("It should be used for the transaction category", func() {
+			So(model.Transaction{MCC: "6011"}.Category(), ShouldEqual, model.CategoryCash)
+		})
+	})
+}
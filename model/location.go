@@ -0,0 +1,38 @@
+package model
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth, used for calculating distances.
+const earthRadiusKm = 6371.0
+
+// Location represents the physical location of a merchant where a transaction
+// is being made. All of its fields are optional.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country of the merchant
+	// (e.g. "BR").
+	Country string `json:"country,omitempty"`
+	// Coordinates are the geographic coordinates of the merchant.
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+}
+
+// Coordinates represent a geographic position on the Earth, in degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// DistanceKm returns the great-circle distance between the coordinates and
+// another position in kilometers, calculated with the haversine formula.
+func (c Coordinates) DistanceKm(other Coordinates) float64 {
+	lat1, lat2 := toRadians(c.Latitude), toRadians(other.Latitude)
+	deltaLat := lat2 - lat1
+	deltaLon := toRadians(other.Longitude - c.Longitude)
+
+	a := math.Pow(math.Sin(deltaLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(deltaLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
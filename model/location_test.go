@@ -0,0 +1,27 @@
+package model_test
+
+import (
+	"nuledger/model"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCoordinatesDistance(t *testing.T) {
+	Convey("Given some coordinates", t, func() {
+		saoPaulo := model.Coordinates{Latitude: -23.5505, Longitude: -46.6333}
+		rio := model.Coordinates{Latitude: -22.9068, Longitude: -43.1729}
+		lisbon := model.Coordinates{Latitude: 38.7223, Longitude: -9.1393}
+
+		Convey("Distance to itself should be zero", func() {
+			So(saoPaulo.DistanceKm(saoPaulo), ShouldEqual, 0)
+		})
+		Convey("Distance should be symmetric", func() {
+			So(saoPaulo.DistanceKm(rio), ShouldEqual, rio.DistanceKm(saoPaulo))
+		})
+		Convey("Distance should match the known ones", func() {
+			So(saoPaulo.DistanceKm(rio), ShouldAlmostEqual, 361, 5)
+			So(saoPaulo.DistanceKm(lisbon), ShouldAlmostEqual, 7930, 50)
+		})
+	})
+}
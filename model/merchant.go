@@ -0,0 +1,11 @@
+package model
+
+// MerchantListUpdate is a request for replacing one of the lists of merchant
+// patterns of an existing account, like its merchant allowlist or blocklist.
+type MerchantListUpdate struct {
+	// AccountID is the unique identifier of the account to be updated.
+	AccountID string `json:"accountId"`
+	// Merchants are the new patterns of merchant names of the list, replacing
+	// any previous ones. An empty list clears it.
+	Merchants []string `json:"merchants"`
+}
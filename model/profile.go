@@ -0,0 +1,11 @@
+package model
+
+// ProfileUpdate is a request for switching the product profile of an existing
+// account, and thus the rules used to authorize its transactions.
+type ProfileUpdate struct {
+	// AccountID is the unique identifier of the account to be updated.
+	AccountID string `json:"accountId"`
+	// Profile is the name of the new profile of the account. An empty name
+	// switches it back to the default rules.
+	Profile string `json:"profile"`
+}
@@ -0,0 +1,30 @@
+package model
+
+// RiskSignal is an enum of the signals that can contribute to the risk score
+// of a transaction, each of them with its own weight.
+type RiskSignal string
+
+const (
+	// RiskSignalNewMerchant is present when the account has never had a
+	// transaction executed in the merchant before.
+	RiskSignalNewMerchant RiskSignal = "new-merchant"
+	// RiskSignalUnusualAmount is present when the amount is much higher than
+	// the usual amounts of the account transactions.
+	RiskSignalUnusualAmount RiskSignal = "unusual-amount"
+	// RiskSignalHighVelocity is present when there have been many recent
+	// transactions in the account.
+	RiskSignalHighVelocity RiskSignal = "high-velocity"
+	// RiskSignalNightTime is present when the transaction happens during the
+	// night in the account time zone.
+	RiskSignalNightTime RiskSignal = "night-time"
+	// RiskSignalForeignCountry is present when the transaction happens in a
+	// country other than the account home country.
+	RiskSignalForeignCountry RiskSignal = "foreign-country"
+)
+
+// RiskAssessment is the result of the risk analysis of a transaction, with its
+// total score and the signals that contributed to it.
+type RiskAssessment struct {
+	Score   float64      `json:"score"`
+	Signals []RiskSignal `json:"signals"`
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// holidayLayout is the layout of the dates of the holidays in a Schedule.
+const holidayLayout = "2006-01-02"
+
+// Schedule represents the periods of time when the card of an account can be
+// used, like business hours of a corporate card. The times are always relative
+// to the local time of the account.
+type Schedule struct {
+	// Windows are the periods of the week when transactions are allowed. A
+	// transaction only needs to be within one of them to be allowed. If empty,
+	// transactions are allowed at any time except on the holidays.
+	Windows []ScheduleWindow `json:"windows,omitempty"`
+	// Holidays are the dates (in the "2006-01-02" format) when no transactions
+	// are allowed at all, regardless of the windows.
+	Holidays []string `json:"holidays,omitempty"`
+}
+
+// ScheduleWindow is a period of hours in some days of the week.
+type ScheduleWindow struct {
+	// Weekdays are the lowercase names of the days of the week of the window
+	// (e.g. "monday"). If empty, the window applies to every day.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// From is the hour of the day when the window starts, inclusive.
+	From int `json:"from"`
+	// To is the hour of the day when the window ends, exclusive. A window that
+	// lasts until midnight must have a To of 24.
+	To int `json:"to"`
+}
+
+// Validate checks that all the windows and holidays of the schedule are valid,
+// returning an error describing the first invalid one otherwise.
+func (s Schedule) Validate() error {
+	for _, window := range s.Windows {
+		if window.From < 0 || window.To > 24 || window.From >= window.To {
+			return fmt.Errorf("Invalid schedule window hours: from %d to %d", window.From, window.To)
+		}
+		for _, weekday := range window.Weekdays {
+			if _, ok := parseWeekday(weekday); !ok {
+				return fmt.Errorf("Invalid schedule weekday: %q", weekday)
+			}
+		}
+	}
+	for _, holiday := range s.Holidays {
+		if _, err := time.Parse(holidayLayout, holiday); err != nil {
+			return fmt.Errorf("Invalid schedule holiday: %w", err)
+		}
+	}
+	return nil
+}
+
+// Allows returns whether the given local time is within any of the windows of
+// the schedule (or the schedule has no windows) and not on one of its holidays.
+func (s Schedule) Allows(t time.Time) bool {
+	date := t.Format(holidayLayout)
+	for _, holiday := range s.Holidays {
+		if holiday == date {
+			return false
+		}
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, window := range s.Windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w ScheduleWindow) contains(t time.Time) bool {
+	if hour := t.Hour(); hour < w.From || hour >= w.To {
+		return false
+	}
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, name := range w.Weekdays {
+		if weekday, ok := parseWeekday(name); ok && weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a deep copy of the schedule.
+func (s *Schedule) copy() *Schedule {
+	if s == nil {
+		return nil
+	}
+	copy := Schedule{Holidays: copyStrings(s.Holidays)}
+	for _, window := range s.Windows {
+		window.Weekdays = copyStrings(window.Weekdays)
+		copy.Windows = append(copy.Windows, window)
+	}
+	return &copy
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+package model_test
+
+import (
+	"nuledger/model"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	Convey("Given a business hours schedule", t, func() {
+		schedule := model.Schedule{
+			Windows: []model.ScheduleWindow{
+				{Weekdays: []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, From: 9, To: 18},
+				{Weekdays: []string{"Saturday"}, From: 9, To: 12},
+			},
+			Holidays: []string{"2021-04-02"},
+		}
+		// April 1st 2021 is a Thursday
+		at := func(day, hour, min int) time.Time {
+			return time.Date(2021, time.April, day, hour, min, 0, 0, time.UTC)
+		}
+
+		Convey("It should be valid", func() {
+			So(schedule.Validate(), ShouldBeNil)
+		})
+		Convey("It should allow times within its windows", func() {
+			So(schedule.Allows(at(1, 9, 0)), ShouldBeTrue)
+			So(schedule.Allows(at(1, 17, 59)), ShouldBeTrue)
+			So(schedule.Allows(at(3, 11, 30)), ShouldBeTrue)
+		})
+		Convey("It should NOT allow times outside its windows", func() {
+			So(schedule.Allows(at(1, 8, 59)), ShouldBeFalse)
+			So(schedule.Allows(at(1, 18, 0)), ShouldBeFalse)
+			So(schedule.Allows(at(3, 12, 0)), ShouldBeFalse)
+			So(schedule.Allows(at(4, 10, 0)), ShouldBeFalse)
+		})
+		Convey("It should NOT allow times on holidays", func() {
+			So(schedule.Allows(at(2, 10, 0)), ShouldBeFalse)
+		})
+		Convey("It should validate its windows and holidays", func() {
+			So(model.Schedule{Windows: []model.ScheduleWindow{{From: 10, To: 10}}}.Validate(), ShouldNotBeNil)
+			So(model.Schedule{Windows: []model.ScheduleWindow{{From: 0, To: 25}}}.Validate(), ShouldNotBeNil)
+			So(model.Schedule{Windows: []model.ScheduleWindow{{Weekdays: []string{"funday"}, From: 0, To: 24}}}.Validate(), ShouldNotBeNil)
+			So(model.Schedule{Holidays: []string{"25/12/2021"}}.Validate(), ShouldNotBeNil)
+		})
+		Convey("A window without weekdays should apply to every day", func() {
+			allDay := model.Schedule{Windows: []model.ScheduleWindow{{From: 0, To: 24}}}
+			So(allDay.Allows(at(4, 23, 59)), ShouldBeTrue)
+		})
+		Convey("A schedule without windows should only restrict its holidays", func() {
+			holidaysOnly := model.Schedule{Holidays: []string{"2021-04-02"}}
+			So(holidaysOnly.Validate(), ShouldBeNil)
+			So(holidaysOnly.Allows(at(1, 3, 0)), ShouldBeTrue)
+			So(holidaysOnly.Allows(at(4, 23, 59)), ShouldBeTrue)
+			So(holidaysOnly.Allows(at(2, 10, 0)), ShouldBeFalse)
+		})
+	})
+}
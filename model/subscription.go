@@ -0,0 +1,46 @@
+package model
+
+import "strings"
+
+// Subscription is a recurring agreement between the account holder and some
+// merchant, allowing the merchant to charge the account periodically up to an
+// agreed amount.
+type Subscription struct {
+	// ID is the unique identifier of the subscription, sent by the merchant in
+	// the SubscriptionID of its recurring transactions.
+	ID string `json:"id"`
+	// Merchant is the optional name of the merchant of the subscription. If
+	// set, only transactions from that merchant (ignoring case) match it.
+	Merchant string `json:"merchant,omitempty"`
+	// MaxAmount is the maximum amount agreed for each recurring charge.
+	MaxAmount int64 `json:"max-amount"`
+}
+
+// SubscriptionsUpdate is a request for replacing the registered subscriptions
+// of an existing account.
+type SubscriptionsUpdate struct {
+	// AccountID is the unique identifier of the account to be updated.
+	AccountID string `json:"accountId"`
+	// Subscriptions are the new recurring agreements of the account, replacing
+	// any previous ones. An empty list clears them.
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// SubscriptionOf returns the subscription registered in the account which the
+// given transaction is a recurring charge of, if any. Transactions that are not
+// flagged as recurring never match a subscription.
+func (a Account) SubscriptionOf(transaction Transaction) (Subscription, bool) {
+	if !transaction.Recurring || transaction.SubscriptionID == "" {
+		return Subscription{}, false
+	}
+	for _, subscription := range a.Subscriptions {
+		if subscription.ID != transaction.SubscriptionID {
+			continue
+		}
+		if subscription.Merchant != "" && !strings.EqualFold(subscription.Merchant, transaction.Merchant) {
+			continue
+		}
+		return subscription, true
+	}
+	return Subscription{}, false
+}
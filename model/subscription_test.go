@@ -0,0 +1,42 @@
+package model_test
+
+import (
+	"nuledger/model"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubscriptionOf(t *testing.T) {
+	Convey("Given an account with subscriptions", t, func() {
+		streaming := model.Subscription{ID: "sub-1", Merchant: "Streaming Co", MaxAmount: 30}
+		gym := model.Subscription{ID: "sub-2", MaxAmount: 100}
+		account := model.Account{Subscriptions: []model.Subscription{streaming, gym}}
+
+		test := func(transaction model.Transaction) (model.Subscription, bool) {
+			return account.SubscriptionOf(transaction)
+		}
+
+		Convey("It should match recurring transactions of registered subscriptions", func() {
+			subscription, ok := test(model.Transaction{Merchant: "STREAMING CO", Recurring: true, SubscriptionID: "sub-1"})
+			So(ok, ShouldBeTrue)
+			So(subscription, ShouldResemble, streaming)
+
+			subscription, ok = test(model.Transaction{Merchant: "Any Gym", Recurring: true, SubscriptionID: "sub-2"})
+			So(ok, ShouldBeTrue)
+			So(subscription, ShouldResemble, gym)
+		})
+		Convey("It should NOT match transactions that are not recurring", func() {
+			_, ok := test(model.Transaction{Merchant: "Streaming Co", SubscriptionID: "sub-1"})
+			So(ok, ShouldBeFalse)
+		})
+		Convey("It should NOT match unknown subscriptions or other merchants", func() {
+			_, ok := test(model.Transaction{Merchant: "Streaming Co", Recurring: true, SubscriptionID: "sub-3"})
+			So(ok, ShouldBeFalse)
+			_, ok = test(model.Transaction{Merchant: "Other Co", Recurring: true, SubscriptionID: "sub-1"})
+			So(ok, ShouldBeFalse)
+			_, ok = test(model.Transaction{Merchant: "Streaming Co", Recurring: true})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"nuledger/model/violation"
+	"time"
+)
+
+// RuleOutcome is an enum of the possible outcomes of a rule when authorizing a
+// transaction.
+type RuleOutcome string
+
+const (
+	// RuleOutcomePass means the rule allowed the transaction.
+	RuleOutcomePass RuleOutcome = "pass"
+	// RuleOutcomeFail means the rule declined the transaction.
+	RuleOutcomeFail RuleOutcome = "fail"
+	// RuleOutcomeSkip means the rule was not evaluated for the transaction,
+	// e.g. because it doesn't apply to it or an earlier rule already failed.
+	RuleOutcomeSkip RuleOutcome = "skip"
+)
+
+// RuleDecision is the decision of a single rule when authorizing a transaction,
+// used for explaining why it was declined (or not).
+type RuleDecision struct {
+	// Rule is the name of the rule.
+	Rule string `json:"rule"`
+	// Outcome is whether the rule allowed, declined or skipped the transaction.
+	Outcome RuleOutcome `json:"outcome"`
+	// Violations are the codes of the violations returned by the rule, if any.
+	Violations []violation.Code `json:"violations,omitempty"`
+	// Message is the message of the error returned by the rule, if any.
+	Message string `json:"message,omitempty"`
+	// Duration is how long the rule took to evaluate the transaction.
+	Duration time.Duration `json:"duration-ns"`
+}
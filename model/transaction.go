@@ -5,14 +5,34 @@ import "time"
 // Transaction is an authorization request for a transaction, consisting of
 // information about the merchant, amount to be charged and time.
 type Transaction struct {
+	// ID is an optional unique identifier of the transaction, used to refer to
+	// it in the details of violations caused by it (e.g. double transactions).
+	ID string `json:"id,omitempty"`
 	// AccountID is the unique identifier of the account perforing the
 	// respective transaction.
 	AccountID string `json:"accountId"`
 	// Merchant is a unique string to represent the merchant with which a
 	// transaction is being made.
 	Merchant string `json:"merchant"`
+	// MCC is the merchant category code (a 4-digit ISO 18245 code) of the
+	// merchant, which classifies it in a Category.
+	MCC string `json:"mcc,omitempty"`
 	// Amount is the units of currency that the transaction would be consuming.
 	Amount int64 `json:"amount"`
 	// Time is the exact time on which the transaction was attempted.
 	Time time.Time `json:"time"`
+	// Location is the optional physical location of the merchant.
+	Location *Location `json:"location,omitempty"`
+	// Recurring flags the transaction as a periodic charge from the merchant,
+	// like a subscription fee, instead of one started by the account holder.
+	Recurring bool `json:"recurring,omitempty"`
+	// SubscriptionID is the identifier of the subscription that a recurring
+	// transaction is charging, as registered in the account.
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+}
+
+// Category returns the merchant category of the transaction according to its
+// merchant category code.
+func (t Transaction) Category() Category {
+	return CategoryOf(t.MCC)
 }
@@ -13,4 +13,19 @@ const (
 	CardNotActive                   = "card-not-active"
 	HighFrequencySmallInterval      = "high-frequency-small-interval"
 	DoubleTransaction               = "double-transaction"
+	OverdraftLimitExceeded          = "overdraft-limit-exceeded"
+	SpendLimitExceeded              = "spend-limit-exceeded"
+	CategoryBlocked                 = "category-blocked"
+	CategoryLimitExceeded           = "category-limit-exceeded"
+	MerchantNotAllowed              = "merchant-not-allowed"
+	MerchantQuarantined             = "merchant-quarantined"
+	ImpossibleTravel                = "impossible-travel"
+	CountryNotAllowed               = "country-not-allowed"
+	OutsideAllowedSchedule          = "outside-allowed-schedule"
+	HighRisk                        = "high-risk"
+	AmountAnomaly                   = "amount-anomaly"
+	CardTestingSuspected            = "card-testing-suspected"
+	SubscriptionAmountExceeded      = "subscription-amount-exceeded"
+	UnknownProfile                  = "unknown-profile"
+	InvalidAccountConfig            = "invalid-account-configuration"
 )
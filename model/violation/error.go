@@ -9,11 +9,29 @@ type Error struct {
 	// A free-form message that can contain a friendly description of the
 	// violation and/or any additional info from the context.
 	Message string
+	// Optional structured details about the violation, specific to the rule
+	// that returned it (e.g. which limit was exceeded). It must be of a
+	// comparable type, so that the error itself remains comparable.
+	Details interface{}
 }
 
 // NewError creates a new violation error with the provided code and message.
 func NewError(code Code, format string, args ...interface{}) Error {
-	return Error{code, fmt.Sprintf(format, args...)}
+	return Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails returns a copy of the violation error with the given structured
+// details about the violation attached to it.
+func (e Error) WithDetails(details interface{}) Error {
+	e.Details = details
+	return e
+}
+
+// Is reports whether the target is a violation error with the same code, so
+// that errors.Is matches the violations regardless of their message or details.
+func (e Error) Is(target error) bool {
+	verr, ok := target.(Error)
+	return ok && verr.Code == e.Code
 }
 
 // Error implements the error interface to return the error message as a string.
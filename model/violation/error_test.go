@@ -1,6 +1,8 @@
 package violation_test
 
 import (
+	"errors"
+	"fmt"
 	"nuledger/model/violation"
 	"testing"
 
@@ -23,5 +25,19 @@ func TestError(t *testing.T) {
 		Convey("Return the Message field in Error function", func() {
 			So(err.Error(), ShouldEqual, err.Message)
 		})
+
+		Convey("Attach details only to a copy of it", func() {
+			detailed := err.WithDetails(42)
+			So(detailed.Details, ShouldEqual, 42)
+			So(detailed.Code, ShouldEqual, err.Code)
+			So(err.Details, ShouldBeNil)
+		})
+
+		Convey("Match other violations with the same code", func() {
+			So(errors.Is(err.WithDetails(42), err), ShouldBeTrue)
+			So(errors.Is(fmt.Errorf("wrapped: %w", err), violation.NewError(code, "Another message")), ShouldBeTrue)
+			So(errors.Is(err, violation.NewError("another-code", "Custom error message")), ShouldBeFalse)
+			So(errors.Is(err, errors.New(err.Message)), ShouldBeFalse)
+		})
 	})
 }
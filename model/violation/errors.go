@@ -7,4 +7,15 @@ var (
 	ErrorInsufficientLimit          = NewError(InsufficientLimit, "Transaction amount is higher than available limit")
 	ErrorHighFrequencySmallInterval = NewError(HighFrequencySmallInterval, "Too many transactions in a small interval")
 	ErrorDoubleTransaction          = NewError(DoubleTransaction, "Duplicate transaction of same amount and merchant")
+	ErrorOverdraftLimitExceeded     = NewError(OverdraftLimitExceeded, "Transaction amount exceeds the account overdraft allowance")
+	ErrorSpendLimitExceeded         = NewError(SpendLimitExceeded, "Too much spent in transactions within the interval")
+	ErrorCategoryBlocked            = NewError(CategoryBlocked, "Transactions of the merchant category are blocked in the account")
+	ErrorCategoryLimitExceeded      = NewError(CategoryLimitExceeded, "Too much spent in the merchant category within the calendar window")
+	ErrorMerchantNotAllowed         = NewError(MerchantNotAllowed, "Merchant is not allowed by the account merchant lists")
+	ErrorCountryNotAllowed          = NewError(CountryNotAllowed, "Country is not allowed by the account country lists")
+	ErrorOutsideAllowedSchedule     = NewError(OutsideAllowedSchedule, "Transaction time is outside the account allowed schedule")
+	ErrorCardTestingSuspected       = NewError(CardTestingSuspected, "Too many small transactions in distinct merchants, card has been blocked")
+	ErrorSubscriptionAmountExceeded = NewError(SubscriptionAmountExceeded, "Recurring charge is higher than the agreed subscription amount")
+	ErrorUnknownProfile             = NewError(UnknownProfile, "Account profile is not registered in the ledger")
+	ErrorInvalidAccountConfig       = NewError(InvalidAccountConfig, "Account time zone or schedule is invalid")
 )
@@ -0,0 +1,123 @@
+package violation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Locale identifies the language of the localized violation messages, either
+// only the language (e.g. "pt") or also its region (e.g. "pt-BR").
+type Locale string
+
+const (
+	LocaleEnglish    Locale = "en"
+	LocalePortuguese Locale = "pt"
+	LocaleSpanish    Locale = "es"
+)
+
+// catalogs holds the message templates of the violation codes in each locale.
+var catalogs = map[Locale]map[Code]*template.Template{}
+
+// RegisterMessages adds the message templates of the given violation codes to
+// the catalog of a locale, replacing any previous messages of the same codes.
+//
+// The messages are text/template templates executed with the params of the
+// violation, which are its details as written in the structured output (e.g.
+// {{.remaining}}, or {{index . "retry-after"}} for names that aren't valid Go
+// identifiers). It returns an error if any of the templates is invalid.
+func RegisterMessages(locale Locale, messages map[Code]string) error {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		return fmt.Errorf("Locale must not be empty")
+	}
+	templates := make(map[Code]*template.Template, len(messages))
+	for code, message := range messages {
+		tmpl, err := template.New(string(code)).Option("missingkey=error").Parse(message)
+		if err != nil {
+			return fmt.Errorf("Invalid %s message of violation %s: %w", locale, code, err)
+		}
+		templates[code] = tmpl
+	}
+
+	if catalogs[locale] == nil {
+		catalogs[locale] = map[Code]*template.Template{}
+	}
+	for code, tmpl := range templates {
+		catalogs[locale][code] = tmpl
+	}
+	return nil
+}
+
+// MustRegisterMessages registers the given messages like RegisterMessages, but
+// panics in case of errors.
+func MustRegisterMessages(locale Locale, messages map[Code]string) {
+	if err := RegisterMessages(locale, messages); err != nil {
+		panic(err)
+	}
+}
+
+// Localize returns the message of the violation in the given locale, or in its
+// language if there is no message for the region (e.g. "pt" for "pt-BR"). The
+// English catalog is used for the empty locale and for the codes missing from
+// the catalogs of the locale, so it is the single source of the messages in
+// English. It only falls back to the message of the violation error itself if
+// there is no such message in any of those catalogs, or if the violation lacks
+// any of its params.
+func Localize(verr Error, locale Locale) string {
+	tmpl := lookupMessage(verr.Code, normalizeLocale(locale))
+	if tmpl == nil {
+		return verr.Message
+	}
+	params, err := messageParams(verr.Details)
+	if err != nil {
+		return verr.Message
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, params); err != nil {
+		return verr.Message
+	}
+	return message.String()
+}
+
+// lookupMessage returns the message template of the code in the locale, in its
+// language or in English, or nil if there is none.
+func lookupMessage(code Code, locale Locale) *template.Template {
+	if tmpl := catalogs[locale][code]; tmpl != nil {
+		return tmpl
+	}
+	if idx := strings.Index(string(locale), "-"); idx >= 0 {
+		if tmpl := catalogs[locale[:idx]][code]; tmpl != nil {
+			return tmpl
+		}
+	}
+	return catalogs[LocaleEnglish][code]
+}
+
+// messageParams returns the params of a violation with the given details, which
+// are the fields of their JSON representation (numbers are kept as written, so
+// they aren't formatted as floats).
+func messageParams(details interface{}) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if details == nil {
+		return params, nil
+	}
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// normalizeLocale returns the locale in lower case with its parts separated by
+// a hyphen, e.g. "pt-br" for "pt_BR".
+func normalizeLocale(locale Locale) Locale {
+	return Locale(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(string(locale)), "_", "-")))
+}
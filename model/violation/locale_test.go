@@ -0,0 +1,79 @@
+package violation_test
+
+import (
+	"nuledger/model/violation"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocalize(t *testing.T) {
+	Convey("Given the violation message catalogs", t, func() {
+		type limitDetails struct {
+			Remaining int64 `json:"remaining"`
+		}
+		type frequencyDetails struct {
+			RetryAfter *time.Time `json:"retry-after,omitempty"`
+		}
+
+		Convey("It should localize the messages with their params", func() {
+			verr := violation.ErrorInsufficientLimit.WithDetails(&limitDetails{Remaining: 1000000})
+			So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual,
+				"O valor da transação é maior que o limite disponível de 1000000")
+			So(violation.Localize(verr, violation.LocaleSpanish), ShouldEqual,
+				"El monto de la transacción es mayor que el límite disponible de 1000000")
+			So(violation.Localize(verr, violation.LocaleEnglish), ShouldEqual,
+				"Transaction amount is higher than available limit of 1000000")
+		})
+
+		Convey("It should include optional params only if present", func() {
+			retryAfter := time.Date(2021, time.April, 1, 16, 5, 0, 0, time.UTC)
+			verr := violation.ErrorDoubleTransaction.WithDetails(&frequencyDetails{RetryAfter: &retryAfter})
+			So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual,
+				"Transação duplicada com o mesmo valor e estabelecimento, tente novamente após 2021-04-01T16:05:00Z")
+
+			verr = violation.ErrorDoubleTransaction.WithDetails(&frequencyDetails{})
+			So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual,
+				"Transação duplicada com o mesmo valor e estabelecimento")
+		})
+
+		Convey("It should use the language of locales with regions", func() {
+			So(violation.Localize(violation.ErrorCardNotActive, "pt-BR"), ShouldEqual, "O cartão da conta não está ativo")
+			So(violation.Localize(violation.ErrorCardNotActive, "es_AR"), ShouldEqual, "La tarjeta de la cuenta no está activa")
+		})
+
+		Convey("It should fall back to the English catalog", func() {
+			verr := violation.ErrorInsufficientLimit.WithDetails(&limitDetails{Remaining: 1000000})
+			Convey("For the empty locale", func() {
+				So(violation.Localize(verr, ""), ShouldEqual, "Transaction amount is higher than available limit of 1000000")
+			})
+			Convey("For unknown locales", func() {
+				So(violation.Localize(verr, "fr"), ShouldEqual, "Transaction amount is higher than available limit of 1000000")
+			})
+		})
+
+		Convey("It should fall back to the violation message", func() {
+			Convey("For codes missing from the catalog", func() {
+				verr := violation.NewError("unlocalized-violation", "Hello violations")
+				So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual, "Hello violations")
+			})
+			Convey("For violations missing any params", func() {
+				So(violation.Localize(violation.ErrorInsufficientLimit, violation.LocalePortuguese), ShouldEqual,
+					violation.ErrorInsufficientLimit.Message)
+			})
+		})
+
+		Convey("It should register messages of other codes", func() {
+			verr := violation.NewError("localized-test-violation", "Hello violations").WithDetails(&limitDetails{Remaining: 42})
+			So(violation.RegisterMessages("pt-BR", map[violation.Code]string{verr.Code: "Olá violações, restam {{.remaining}}"}), ShouldBeNil)
+			So(violation.Localize(verr, "pt-BR"), ShouldEqual, "Olá violações, restam 42")
+			So(violation.Localize(verr, violation.LocalePortuguese), ShouldEqual, "Hello violations")
+		})
+
+		Convey("It should reject invalid messages", func() {
+			So(violation.RegisterMessages("pt", map[violation.Code]string{"broken": "{{.remaining"}), ShouldNotBeNil)
+			So(violation.RegisterMessages("", map[violation.Code]string{"broken": "Broken"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package violation
+
+// retryAfter is the part of the messages of the frequency violations telling
+// when to retry, if known, in each locale.
+const (
+	retryAfterEnglish    = `{{with index . "retry-after"}}, try again after {{.}}{{end}}`
+	retryAfterPortuguese = `{{with index . "retry-after"}}, tente novamente após {{.}}{{end}}`
+	retryAfterSpanish    = `{{with index . "retry-after"}}, intente nuevamente después de {{.}}{{end}}`
+)
+
+// init registers the built-in catalogs of messages of the well-known codes.
+func init() {
+	MustRegisterMessages(LocaleEnglish, map[Code]string{
+		AccountAlreadyInitialized:  "Account has already been initialized",
+		AccountNotInitialized:      "Account hasn't been initialized",
+		CardNotActive:              "Account card is not active",
+		InsufficientLimit:          "Transaction amount is higher than available limit of {{.remaining}}",
+		HighFrequencySmallInterval: "Too many transactions in a small interval" + retryAfterEnglish,
+		DoubleTransaction:          "Duplicate transaction of same amount and merchant" + retryAfterEnglish,
+		OverdraftLimitExceeded:     "Transaction amount exceeds the account overdraft allowance, {{.remaining}} remaining",
+		SpendLimitExceeded:         "Too much spent in transactions within the interval, {{.remaining}} remaining",
+		CategoryBlocked:            "Transactions of the merchant category are blocked in the account",
+		CategoryLimitExceeded:      "Too much spent in the merchant category within the calendar window, {{.remaining}} remaining",
+		MerchantNotAllowed:         "Merchant is not allowed by the account merchant lists",
+		MerchantQuarantined:        "Merchant is quarantined after a burst of transactions across the accounts",
+		ImpossibleTravel:           "Transaction location is too far from the previous one for the time between them",
+		CountryNotAllowed:          "Country is not allowed by the account country lists",
+		OutsideAllowedSchedule:     "Transaction time is outside the account allowed schedule",
+		HighRisk:                   "Transaction risk score is above the threshold (score {{.score}})",
+		AmountAnomaly:              "Transaction amount is anomalous compared to the account history",
+		CardTestingSuspected:       "Too many small transactions in distinct merchants, card has been blocked",
+		SubscriptionAmountExceeded: "Recurring charge is higher than the agreed subscription amount",
+		UnknownProfile:             "Account profile is not registered in the ledger",
+		InvalidAccountConfig:       "Account time zone or schedule is invalid",
+	})
+
+	MustRegisterMessages(LocalePortuguese, map[Code]string{
+		AccountAlreadyInitialized:  "A conta já foi inicializada",
+		AccountNotInitialized:      "A conta não foi inicializada",
+		CardNotActive:              "O cartão da conta não está ativo",
+		InsufficientLimit:          "O valor da transação é maior que o limite disponível de {{.remaining}}",
+		HighFrequencySmallInterval: "Muitas transações em um curto intervalo" + retryAfterPortuguese,
+		DoubleTransaction:          "Transação duplicada com o mesmo valor e estabelecimento" + retryAfterPortuguese,
+		OverdraftLimitExceeded:     "O valor da transação excede o limite de cheque especial da conta, restam {{.remaining}}",
+		SpendLimitExceeded:         "Gasto excessivo em transações no período, restam {{.remaining}}",
+		CategoryBlocked:            "Transações desta categoria de estabelecimento estão bloqueadas na conta",
+		CategoryLimitExceeded:      "Gasto excessivo nesta categoria de estabelecimento no período, restam {{.remaining}}",
+		MerchantNotAllowed:         "O estabelecimento não é permitido pelas listas de estabelecimentos da conta",
+		MerchantQuarantined:        "O estabelecimento está temporariamente em quarentena",
+		ImpossibleTravel:           "A localização da transação está longe demais da anterior",
+		CountryNotAllowed:          "O país não é permitido pelas listas de países da conta",
+		OutsideAllowedSchedule:     "O horário da transação está fora do horário permitido da conta",
+		HighRisk:                   "O risco da transação é alto demais (pontuação {{.score}})",
+		AmountAnomaly:              "O valor da transação é incomum para a conta",
+		CardTestingSuspected:       "Muitas transações pequenas em estabelecimentos diferentes, o cartão foi bloqueado",
+		SubscriptionAmountExceeded: "A cobrança recorrente é maior que o valor acordado da assinatura",
+		UnknownProfile:             "O perfil da conta não está registrado",
+		InvalidAccountConfig:       "O fuso horário ou o horário permitido da conta é inválido",
+	})
+
+	MustRegisterMessages(LocaleSpanish, map[Code]string{
+		AccountAlreadyInitialized:  "La cuenta ya fue inicializada",
+		AccountNotInitialized:      "La cuenta no fue inicializada",
+		CardNotActive:              "La tarjeta de la cuenta no está activa",
+		InsufficientLimit:          "El monto de la transacción es mayor que el límite disponible de {{.remaining}}",
+		HighFrequencySmallInterval: "Demasiadas transacciones en un intervalo corto" + retryAfterSpanish,
+		DoubleTransaction:          "Transacción duplicada con el mismo monto y comercio" + retryAfterSpanish,
+		OverdraftLimitExceeded:     "El monto de la transacción excede el sobregiro permitido de la cuenta, quedan {{.remaining}}",
+		SpendLimitExceeded:         "Demasiado gasto en transacciones en el período, quedan {{.remaining}}",
+		CategoryBlocked:            "Las transacciones de esta categoría de comercio están bloqueadas en la cuenta",
+		CategoryLimitExceeded:      "Demasiado gasto en esta categoría de comercio en el período, quedan {{.remaining}}",
+		MerchantNotAllowed:         "El comercio no está permitido por las listas de comercios de la cuenta",
+		MerchantQuarantined:        "El comercio está temporalmente en cuarentena",
+		ImpossibleTravel:           "La ubicación de la transacción está demasiado lejos de la anterior",
+		CountryNotAllowed:          "El país no está permitido por las listas de países de la cuenta",
+		OutsideAllowedSchedule:     "La hora de la transacción está fuera del horario permitido de la cuenta",
+		HighRisk:                   "El riesgo de la transacción es demasiado alto (puntaje {{.score}})",
+		AmountAnomaly:              "El monto de la transacción es inusual para la cuenta",
+		CardTestingSuspected:       "Demasiadas transacciones pequeñas en comercios distintos, la tarjeta fue bloqueada",
+		SubscriptionAmountExceeded: "El cargo recurrente es mayor que el monto acordado de la suscripción",
+		UnknownProfile:             "El perfil de la cuenta no está registrado",
+		InvalidAccountConfig:       "La zona horaria o el horario permitido de la cuenta no es válido",
+	})
+}
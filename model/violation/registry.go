@@ -0,0 +1,77 @@
+package violation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity is an enum to represent how serious a violation is, e.g. for
+// prioritizing the review of the declined operations.
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Definition describes a violation code registered in the registry.
+type Definition struct {
+	Code        Code     `json:"code"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+	// Retriable is whether the same operation may succeed if retried later
+	// without any changes to the account, e.g. once some time window ends.
+	Retriable bool `json:"retriable"`
+}
+
+// registry holds the definitions of all the registered violation codes.
+var registry = map[Code]Definition{}
+
+// Register adds the definition of a violation code to the registry, so that
+// the code can be returned by the rules. It returns an error if the definition
+// is invalid or if the code is already registered with a different definition.
+func Register(def Definition) error {
+	if def.Code == "" {
+		return fmt.Errorf("Violation code must not be empty")
+	}
+	switch def.Severity {
+	case SeverityLow, SeverityMedium, SeverityHigh:
+	default:
+		return fmt.Errorf("Unknown severity of violation %s: %q", def.Code, def.Severity)
+	}
+	if existing, ok := registry[def.Code]; ok && existing != def {
+		return fmt.Errorf("Violation %s is already registered with another definition", def.Code)
+	}
+	registry[def.Code] = def
+	return nil
+}
+
+// MustRegister registers the given definitions like Register, but panics in
+// case of errors. It is meant to be called from the init functions of the
+// packages with the rules returning the violations.
+func MustRegister(defs ...Definition) {
+	for _, def := range defs {
+		if err := Register(def); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Lookup returns the definition of the given violation code, and whether it is
+// registered at all.
+func Lookup(code Code) (Definition, bool) {
+	def, ok := registry[code]
+	return def, ok
+}
+
+// Registered returns the definitions of all the registered violation codes,
+// sorted by code.
+func Registered() []Definition {
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Code < defs[j].Code })
+	return defs
+}
@@ -0,0 +1,59 @@
+package violation_test
+
+import (
+	"nuledger/model/violation"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("Given the violation registry", t, func() {
+		def := violation.Definition{
+			Code:        "registry-test-violation",
+			Severity:    violation.SeverityMedium,
+			Description: "Violation registered by the tests",
+			Retriable:   true,
+		}
+
+		Convey("It should look up the registered codes", func() {
+			So(violation.Register(def), ShouldBeNil)
+
+			registered, ok := violation.Lookup(def.Code)
+			So(ok, ShouldBeTrue)
+			So(registered, ShouldResemble, def)
+			So(violation.Registered(), ShouldContain, def)
+		})
+
+		Convey("It should accept registering the same definition again", func() {
+			So(violation.Register(def), ShouldBeNil)
+			So(violation.Register(def), ShouldBeNil)
+		})
+
+		Convey("It should reject conflicting definitions of a code", func() {
+			So(violation.Register(def), ShouldBeNil)
+			def.Severity = violation.SeverityHigh
+			So(violation.Register(def), ShouldNotBeNil)
+			So(func() { violation.MustRegister(def) }, ShouldPanic)
+		})
+
+		Convey("It should reject invalid definitions", func() {
+			So(violation.Register(violation.Definition{Severity: violation.SeverityLow}), ShouldNotBeNil)
+			So(violation.Register(violation.Definition{Code: "registry-test-no-severity"}), ShouldNotBeNil)
+
+			_, ok := violation.Lookup("registry-test-no-severity")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("It should list the codes sorted", func() {
+			violation.MustRegister(
+				violation.Definition{Code: "registry-test-b", Severity: violation.SeverityLow},
+				violation.Definition{Code: "registry-test-a", Severity: violation.SeverityLow},
+			)
+			defs := violation.Registered()
+			for i := 1; i < len(defs); i++ {
+				So(defs[i-1].Code, ShouldBeLessThan, defs[i].Code)
+			}
+		})
+	})
+}
@@ -0,0 +1,211 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// CalendarWindow is an enum to represent the calendar-aligned windows that can
+// be used for limiting events, as opposed to the rolling intervals used by the
+// RateLimiter and SpendLimiter.
+type CalendarWindow int
+
+const (
+	// Day is the window of a calendar day, starting at midnight.
+	Day CalendarWindow = iota
+	// Week is the window of a calendar week, starting on Monday at midnight.
+	Week
+	// Month is the window of a calendar month, starting at midnight of its
+	// first day.
+	Month
+)
+
+// calendarWindowNames are the textual representations of the calendar windows.
+var calendarWindowNames = map[CalendarWindow]string{
+	Day:   "day",
+	Week:  "week",
+	Month: "month",
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so that the
+// window is represented by its name (e.g. "day") in JSON objects.
+func (w CalendarWindow) MarshalText() ([]byte, error) {
+	name, ok := calendarWindowNames[w]
+	if !ok {
+		return nil, fmt.Errorf("Unknown calendar window: %d", w)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, parsing the
+// name of a window as returned by MarshalText.
+func (w *CalendarWindow) UnmarshalText(text []byte) error {
+	for window, name := range calendarWindowNames {
+		if name == string(text) {
+			*w = window
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown calendar window: %q", text)
+}
+
+// Start returns the beginning of the calendar window which contains the given
+// time. The calendar is the one of the location of the given time, so it must
+// be converted to the desired time zone before calling this function.
+func (w CalendarWindow) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch w {
+	case Week:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// End returns the beginning of the calendar window following the one which
+// contains the given time, analogously to Start.
+func (w CalendarWindow) End(t time.Time) time.Time {
+	start := w.Start(t)
+	switch w {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// LocalTimeLimiter is implemented by the limiters whose behavior depends on the
+// location of the timestamps of the events, like the calendar-aligned ones, so
+// that the events must be converted to the desired time zone before being sent
+// to them.
+type LocalTimeLimiter interface {
+	// UsesLocalTime is only a marker method, which doesn't do anything.
+	UsesLocalTime()
+}
+
+// Ensure the calendar-aligned limiters implement the LocalTimeLimiter interface
+var (
+	_ LocalTimeLimiter = &CalendarRateLimiter{}
+	_ LocalTimeLimiter = &CalendarSpendLimiter{}
+)
+
+// CalendarRateLimiter is a utility to limit the number of events that can
+// happen within the same calendar window, e.g. at most 3 events per calendar
+// day. The timestamps of the events must be sent in ascending order and in the
+// time zone that defines the calendar, so that the beginning of a new window
+// can be detected. The zero value for CalendarRateLimiter is one that never
+// allows any event.
+//
+// Differently from the RateLimiter, it only needs to keep a counter for the
+// current window, so it is cheap to use for any number of events.
+type CalendarRateLimiter struct {
+	// MaxEvents specifies the maximum amount of events allowed in the same
+	// calendar window. If left zero, no events will ever be allowed.
+	MaxEvents int
+	// Window specifies the calendar window in which the events are counted.
+	Window CalendarWindow
+
+	windowStart time.Time
+	count       int
+}
+
+// Allows function checks whether the given event is allowed to happen without
+// making any changes to the internal state of the rate limiter. It returns true
+// if the event is allowed.
+func (l *CalendarRateLimiter) Allows(event time.Time) bool {
+	return l.countInWindow(event) < l.MaxEvents
+}
+
+// Take actually updates the internal state of the rate limiter in order to
+// consider the given event as having happened. It still checks if the event is
+// actually allowed and thus returns whether the event was taken or not.
+func (l *CalendarRateLimiter) Take(event time.Time) bool {
+	count := l.countInWindow(event)
+	if count >= l.MaxEvents {
+		return false
+	}
+	l.windowStart, l.count = l.Window.Start(event), count+1
+	return true
+}
+
+// RetryAfter returns the earliest time, not before the given event, at which an
+// event would be allowed considering the past events already taken, which is
+// the beginning of the next window if the current one is full. It returns false
+// if no event will ever be allowed.
+func (l *CalendarRateLimiter) RetryAfter(event time.Time) (time.Time, bool) {
+	if l.MaxEvents <= 0 {
+		return time.Time{}, false
+	}
+	if l.Allows(event) {
+		return event, true
+	}
+	return l.Window.End(event), true
+}
+
+// UsesLocalTime implements the LocalTimeLimiter interface.
+func (l *CalendarRateLimiter) UsesLocalTime() {}
+
+func (l *CalendarRateLimiter) countInWindow(event time.Time) int {
+	if !l.Window.Start(event).Equal(l.windowStart) {
+		return 0
+	}
+	return l.count
+}
+
+// CalendarSpendLimiter is a utility to limit the total amount of the events
+// that happen within the same calendar window, e.g. spending at most 200 units
+// of currency per calendar day. It has the same requirements regarding the
+// timestamps of the events as the CalendarRateLimiter. The zero value for
+// CalendarSpendLimiter is one that only allows events with no amount at all.
+type CalendarSpendLimiter struct {
+	// MaxAmount specifies the maximum sum of the amounts of the events allowed
+	// in the same calendar window. If left zero, only events with zero amount
+	// will ever be allowed.
+	MaxAmount int64
+	// Window specifies the calendar window in which the amounts are summed.
+	Window CalendarWindow
+
+	windowStart time.Time
+	spent       int64
+}
+
+// Allows function checks whether the given event is allowed to happen without
+// making any changes to the internal state of the limiter. It returns true if
+// the amount of the event would not exceed the maximum allowed amount within
+// the current calendar window.
+func (l *CalendarSpendLimiter) Allows(event time.Time, amount int64) bool {
+	return l.spentInWindow(event)+amount <= l.MaxAmount
+}
+
+// Take actually updates the internal state of the limiter in order to consider
+// the given event as having happened. It still checks if the event is actually
+// allowed and thus returns whether the event was taken or not.
+func (l *CalendarSpendLimiter) Take(event time.Time, amount int64) bool {
+	spent := l.spentInWindow(event)
+	if spent+amount > l.MaxAmount {
+		return false
+	}
+	l.windowStart, l.spent = l.Window.Start(event), spent+amount
+	return true
+}
+
+// Remaining returns the amount that can still be spent in an event at the given
+// time without exceeding the configured maximum amount.
+func (l *CalendarSpendLimiter) Remaining(event time.Time) int64 {
+	return l.MaxAmount - l.spentInWindow(event)
+}
+
+// UsesLocalTime implements the LocalTimeLimiter interface.
+func (l *CalendarSpendLimiter) UsesLocalTime() {}
+
+func (l *CalendarSpendLimiter) spentInWindow(event time.Time) int64 {
+	if !l.Window.Start(event).Equal(l.windowStart) {
+		return 0
+	}
+	return l.spent
+}
@@ -0,0 +1,138 @@
+package util_test
+
+import (
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCalendarWindow(t *testing.T) {
+	Convey("Given a time in some time zone", t, func() {
+		loc := time.FixedZone("UTC-3", -3*60*60)
+		thursday := time.Date(2021, time.April, 1, 23, 30, 0, 0, loc)
+
+		Convey("Day window should start at its midnight", func() {
+			So(util.Day.Start(thursday), ShouldEqual, time.Date(2021, time.April, 1, 0, 0, 0, 0, loc))
+		})
+		Convey("Week window should start on Monday", func() {
+			So(util.Week.Start(thursday), ShouldEqual, time.Date(2021, time.March, 29, 0, 0, 0, 0, loc))
+		})
+		Convey("Week window of a Sunday should start on the previous Monday", func() {
+			sunday := time.Date(2021, time.April, 4, 12, 0, 0, 0, loc)
+			So(util.Week.Start(sunday), ShouldEqual, time.Date(2021, time.March, 29, 0, 0, 0, 0, loc))
+		})
+		Convey("Month window should start on its first day", func() {
+			So(util.Month.Start(thursday), ShouldEqual, time.Date(2021, time.April, 1, 0, 0, 0, 0, loc))
+		})
+		Convey("Windows should end at the start of the next one", func() {
+			So(util.Day.End(thursday), ShouldEqual, time.Date(2021, time.April, 2, 0, 0, 0, 0, loc))
+			So(util.Week.End(thursday), ShouldEqual, time.Date(2021, time.April, 5, 0, 0, 0, 0, loc))
+			So(util.Month.End(thursday), ShouldEqual, time.Date(2021, time.May, 1, 0, 0, 0, 0, loc))
+		})
+		Convey("The window depends on the time zone of the time", func() {
+			So(util.Day.Start(thursday.UTC()), ShouldEqual, time.Date(2021, time.April, 2, 0, 0, 0, 0, time.UTC))
+		})
+	})
+
+	Convey("Given a calendar window", t, func() {
+		Convey("It should be represented by its name in text", func() {
+			text, err := util.Week.MarshalText()
+			So(err, ShouldBeNil)
+			So(string(text), ShouldEqual, "week")
+
+			var window util.CalendarWindow
+			So(window.UnmarshalText(text), ShouldBeNil)
+			So(window, ShouldEqual, util.Week)
+		})
+		Convey("It should return errors for unknown windows", func() {
+			_, err := util.CalendarWindow(42).MarshalText()
+			So(err, ShouldNotBeNil)
+
+			var window util.CalendarWindow
+			So(window.UnmarshalText([]byte("fortnight")), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestCalendarRateLimiter(t *testing.T) {
+	Convey("Given a CalendarRateLimiter", t, func() {
+		limiter := util.CalendarRateLimiter{MaxEvents: 3, Window: util.Day}
+		morning := time.Date(2021, time.April, 1, 8, 0, 0, 0, time.UTC)
+
+		testTake := func(time time.Time) bool {
+			allows := limiter.Allows(time)
+			taken := limiter.Take(time)
+			So(allows, ShouldEqual, taken)
+			return allows
+		}
+
+		Convey("Allows should not affect state", func() {
+			for i := 0; i < 10; i++ {
+				So(limiter.Allows(morning), ShouldBeTrue)
+			}
+		})
+
+		Convey("When the quota of the day is consumed", func() {
+			for i := 0; i < limiter.MaxEvents; i++ {
+				So(testTake(morning), ShouldBeTrue)
+			}
+
+			Convey("It should retry in the next day", func() {
+				retryAfter, ok := limiter.RetryAfter(morning.Add(time.Hour))
+				So(ok, ShouldBeTrue)
+				So(retryAfter, ShouldEqual, time.Date(2021, time.April, 2, 0, 0, 0, 0, time.UTC))
+			})
+			Convey("It should NOT allow events until the end of the day", func() {
+				So(testTake(morning.Add(15*time.Hour+59*time.Minute)), ShouldBeFalse)
+			})
+			Convey("It should allow events right in the next day", func() {
+				So(testTake(morning.Add(16*time.Hour)), ShouldBeTrue)
+			})
+		})
+
+		Convey("Its zero value should never take any event", func() {
+			limiter = util.CalendarRateLimiter{}
+			So(testTake(morning), ShouldBeFalse)
+
+			_, ok := limiter.RetryAfter(morning)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestCalendarSpendLimiter(t *testing.T) {
+	Convey("Given a CalendarSpendLimiter", t, func() {
+		limiter := util.CalendarSpendLimiter{MaxAmount: 200, Window: util.Month}
+		start := time.Date(2021, time.April, 10, 8, 0, 0, 0, time.UTC)
+
+		testTake := func(time time.Time, amount int64) bool {
+			allows := limiter.Allows(time, amount)
+			taken := limiter.Take(time, amount)
+			So(allows, ShouldEqual, taken)
+			return allows
+		}
+
+		Convey("When amounts are spent within the month", func() {
+			So(testTake(start, 150), ShouldBeTrue)
+			So(limiter.Remaining(start), ShouldEqual, 50)
+
+			Convey("It should NOT allow exceeding the maximum amount", func() {
+				So(testTake(start.Add(24*time.Hour), 51), ShouldBeFalse)
+				So(testTake(start.Add(24*time.Hour), 50), ShouldBeTrue)
+			})
+			Convey("It should allow the whole amount in the next month", func() {
+				nextMonth := time.Date(2021, time.May, 1, 0, 0, 0, 0, time.UTC)
+				So(limiter.Remaining(nextMonth), ShouldEqual, 200)
+				So(testTake(nextMonth, 200), ShouldBeTrue)
+			})
+		})
+
+		Convey("Its zero value should only take events with no amount", func() {
+			limiter = util.CalendarSpendLimiter{}
+			So(testTake(start, 1), ShouldBeFalse)
+			So(testTake(start, 0), ShouldBeTrue)
+		})
+	})
+}
@@ -62,6 +62,30 @@ func (l *RateLimiter) Take(event time.Time) bool {
 	return true
 }
 
+// RetryAfter returns the earliest time, not before the given event, at which an
+// event would be allowed considering the past events already taken. It returns
+// false if no event will ever be allowed.
+func (l *RateLimiter) RetryAfter(event time.Time) (time.Time, bool) {
+	if l.MaxEvents <= 0 {
+		return time.Time{}, false
+	}
+	threshold := event.Add(-l.Interval)
+	excess := l.countEventsAfter(threshold) - l.MaxEvents
+	if excess < 0 {
+		return event, true
+	}
+
+	// the oldest events in the interval must leave it, up to the excess one
+	elm := l.pastEvents.Front()
+	for !elm.Value.(time.Time).After(threshold) {
+		elm = elm.Next()
+	}
+	for i := 0; i < excess; i++ {
+		elm = elm.Next()
+	}
+	return elm.Value.(time.Time).Add(l.Interval), true
+}
+
 func (l *RateLimiter) popEventsNotAfter(threshold time.Time) {
 	for l.pastEvents.Len() > 0 {
 		elm := l.pastEvents.Front()
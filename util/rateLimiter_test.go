@@ -29,6 +29,12 @@ func TestRateLimiter(t *testing.T) {
 			return allows
 		}
 
+		Convey("It should retry right away while events are allowed", func() {
+			retryAfter, ok := limiter.RetryAfter(startTime)
+			So(ok, ShouldBeTrue)
+			So(retryAfter, ShouldEqual, startTime)
+		})
+
 		Convey("When events come periodically", func() {
 			Convey("It should allow if at least of minimum period", func() {
 				currTime := startTime
@@ -56,6 +62,12 @@ func TestRateLimiter(t *testing.T) {
 					So(testTake(startTime), ShouldBeTrue)
 				}
 
+				Convey("It should retry right in the end of the interval", func() {
+					retryAfter, ok := limiter.RetryAfter(startTime.Add(limiter.Interval / 2))
+					So(ok, ShouldBeTrue)
+					So(retryAfter, ShouldEqual, startTime.Add(limiter.Interval))
+				})
+
 				Convey("It should only allow new events after the end of the interval", func() {
 					So(testTake(startTime.Add(limiter.Interval/2)), ShouldBeFalse)
 					So(testTake(startTime.Add(limiter.Interval-1*time.Millisecond)), ShouldBeFalse)
@@ -73,6 +85,12 @@ func TestRateLimiter(t *testing.T) {
 				}
 				So(testTake(currTime), ShouldBeFalse)
 
+				Convey("It should retry as soon as the first one expires", func() {
+					retryAfter, ok := limiter.RetryAfter(currTime)
+					So(ok, ShouldBeTrue)
+					So(retryAfter, ShouldEqual, startTime.Add(limiter.Interval))
+				})
+
 				Convey("It should allow 1 event as soon as the first one expires", func() {
 					currTime = startTime.Add(limiter.Interval)
 					So(testTake(currTime), ShouldBeTrue)
@@ -90,6 +108,9 @@ func TestRateLimiter(t *testing.T) {
 			Convey("Its zero value should never take any event", func() {
 				limiter = util.RateLimiter{}
 				So(testTake(startTime), ShouldBeFalse)
+
+				_, ok := limiter.RetryAfter(startTime)
+				So(ok, ShouldBeFalse)
 			})
 			Convey("Non-zero interval with zero max events should never take any event", func() {
 				limiter.MaxEvents = 0
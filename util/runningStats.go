@@ -0,0 +1,47 @@
+package util
+
+import "math"
+
+// RunningStats is a utility to keep the statistics of a stream of values, like
+// the amounts of the transactions of an account, without storing the values
+// themselves. It uses Welford's online algorithm, which is numerically stable
+// and takes constant time and memory for each added value. The zero value for
+// RunningStats is one without any values.
+type RunningStats struct {
+	count int
+	mean  float64
+	m2    float64
+}
+
+// Add includes a new value in the statistics.
+func (s *RunningStats) Add(value float64) {
+	s.count++
+	delta := value - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (value - s.mean)
+}
+
+// Count returns how many values have been added.
+func (s *RunningStats) Count() int {
+	return s.count
+}
+
+// Mean returns the average of the values added, or zero if there are none.
+func (s *RunningStats) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the sample variance of the values added, or zero if there
+// are less than 2 values.
+func (s *RunningStats) Variance() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.count-1)
+}
+
+// StdDev returns the sample standard deviation of the values added, or zero if
+// there are less than 2 values.
+func (s *RunningStats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
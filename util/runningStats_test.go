@@ -0,0 +1,37 @@
+package util_test
+
+import (
+	"nuledger/util"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunningStats(t *testing.T) {
+	Convey("Given empty RunningStats", t, func() {
+		stats := util.RunningStats{}
+
+		Convey("It should have no values", func() {
+			So(stats.Count(), ShouldEqual, 0)
+			So(stats.Mean(), ShouldEqual, 0)
+			So(stats.StdDev(), ShouldEqual, 0)
+		})
+
+		Convey("It should have no variance with a single value", func() {
+			stats.Add(42)
+			So(stats.Count(), ShouldEqual, 1)
+			So(stats.Mean(), ShouldEqual, 42)
+			So(stats.Variance(), ShouldEqual, 0)
+		})
+
+		Convey("It should calculate the mean and sample variance", func() {
+			for _, value := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+				stats.Add(value)
+			}
+			So(stats.Count(), ShouldEqual, 8)
+			So(stats.Mean(), ShouldAlmostEqual, 5)
+			So(stats.Variance(), ShouldAlmostEqual, 32.0/7)
+			So(stats.StdDev()*stats.StdDev(), ShouldAlmostEqual, 32.0/7)
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"container/list"
+	"time"
+)
+
+// SpendLimiter is a utility similar to the RateLimiter, but which limits the
+// total amount summed across the events in a sliding interval instead of only
+// counting them. Each event has an amount associated with it (e.g. the amount
+// spent in a transaction) and its timestamp, which must also be sent in
+// ascending order. The zero value for SpendLimiter is one that only allows
+// events with no amount at all.
+//
+// It uses the same exact algorithm as the RateLimiter, keeping all the events
+// in the past interval, so the same performance considerations apply here.
+type SpendLimiter struct {
+	// MaxAmount specifies the maximum sum of the amounts of the events allowed
+	// in the configured interval. If left zero, only events with zero amount
+	// will ever be allowed.
+	MaxAmount int64
+	// Interval specifies what is the interval to be analyzed for summing the
+	// amount of the events and either allowing them or not. Works together
+	// with the MaxAmount configured above. If left zero, only the amount of
+	// each single event is limited.
+	Interval time.Duration
+
+	pastEvents list.List
+}
+
+// spendEvent is the value stored in the list of past events of a SpendLimiter.
+type spendEvent struct {
+	time   time.Time
+	amount int64
+}
+
+// Allows function checks whether the given event is allowed to happen without
+// making any changes to the internal state of the limiter. It returns true if
+// the amount of the event would not exceed the maximum allowed amount within
+// the current interval.
+func (l *SpendLimiter) Allows(event time.Time, amount int64) bool {
+	return l.amountAfter(event.Add(-l.Interval))+amount <= l.MaxAmount
+}
+
+// Take actually updates the internal state of the limiter in order to consider
+// the given event as having happened. It still checks if the event is actually
+// allowed and thus returns whether the event was taken or not, with the same
+// guarantees of consistency with the Allows function as the RateLimiter.
+func (l *SpendLimiter) Take(event time.Time, amount int64) bool {
+	threshold := event.Add(-l.Interval)
+	l.popEventsNotAfter(threshold)
+	if l.amountAfter(threshold)+amount > l.MaxAmount {
+		return false
+	}
+	l.pastEvents.PushBack(spendEvent{event, amount})
+	return true
+}
+
+// Remaining returns the amount that can still be spent in an event at the given
+// time without exceeding the configured maximum amount.
+func (l *SpendLimiter) Remaining(event time.Time) int64 {
+	return l.MaxAmount - l.amountAfter(event.Add(-l.Interval))
+}
+
+func (l *SpendLimiter) popEventsNotAfter(threshold time.Time) {
+	for l.pastEvents.Len() > 0 {
+		elm := l.pastEvents.Front()
+		value := elm.Value.(spendEvent)
+		if value.time.After(threshold) {
+			break
+		}
+		l.pastEvents.Remove(elm)
+	}
+}
+
+func (l *SpendLimiter) amountAfter(threshold time.Time) int64 {
+	total := int64(0)
+	for elm := l.pastEvents.Back(); elm != nil; elm = elm.Prev() {
+		value := elm.Value.(spendEvent)
+		if !value.time.After(threshold) {
+			break
+		}
+		total += value.amount
+	}
+	return total
+}
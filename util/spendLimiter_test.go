@@ -0,0 +1,66 @@
+package util_test
+
+import (
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpendLimiter(t *testing.T) {
+	Convey("Given a SpendLimiter", t, func() {
+		limiter := util.SpendLimiter{MaxAmount: 100, Interval: 1 * time.Hour}
+
+		Convey("Allows should not affect state", func() {
+			allAllowed := true
+			for i := 0; i < 100; i++ {
+				allAllowed = allAllowed && limiter.Allows(startTime, 100)
+			}
+			So(allAllowed, ShouldBeTrue)
+			So(limiter.Remaining(startTime), ShouldEqual, 100)
+		})
+
+		testTake := func(time time.Time, amount int64) bool {
+			allows := limiter.Allows(time, amount)
+			taken := limiter.Take(time, amount)
+			So(allows, ShouldEqual, taken)
+			return allows
+		}
+
+		Convey("It should NOT allow a single event above the maximum amount", func() {
+			So(testTake(startTime, 101), ShouldBeFalse)
+		})
+
+		Convey("When the amount is consumed in the beginning of the interval", func() {
+			So(testTake(startTime, 60), ShouldBeTrue)
+			So(testTake(startTime.Add(1*time.Minute), 40), ShouldBeTrue)
+			So(limiter.Remaining(startTime.Add(1*time.Minute)), ShouldEqual, 0)
+
+			Convey("It should NOT allow any other amount within the interval", func() {
+				So(testTake(startTime.Add(limiter.Interval-1), 1), ShouldBeFalse)
+			})
+			Convey("It should allow the amount of the first event once it expires", func() {
+				So(testTake(startTime.Add(limiter.Interval), 61), ShouldBeFalse)
+				So(testTake(startTime.Add(limiter.Interval), 60), ShouldBeTrue)
+			})
+			Convey("It should allow the whole amount after the interval", func() {
+				So(testTake(startTime.Add(limiter.Interval+1*time.Minute), 100), ShouldBeTrue)
+			})
+		})
+
+		Convey("Corner cases", func() {
+			Convey("Its zero value should only take events with no amount", func() {
+				limiter = util.SpendLimiter{}
+				So(testTake(startTime, 1), ShouldBeFalse)
+				So(testTake(startTime, 0), ShouldBeTrue)
+			})
+			Convey("Zero interval should only limit each single event", func() {
+				limiter.Interval = 0
+				for i := 0; i < 10; i++ {
+					So(testTake(startTime, 100), ShouldBeTrue)
+				}
+			})
+		})
+	})
+}
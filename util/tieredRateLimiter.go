@@ -0,0 +1,124 @@
+package util
+
+import (
+	"container/list"
+	"time"
+)
+
+// RateTier is a single tier of a TieredRateLimiter, allowing at most MaxEvents
+// to happen within each Interval. Its fields have the same semantics as the
+// ones with the same name in the RateLimiter.
+type RateTier struct {
+	MaxEvents int           `json:"max-events"`
+	Interval  time.Duration `json:"interval"`
+}
+
+// TieredRateLimiter is a utility to limit the rate of events with multiple
+// tiers at the same time, e.g. at most 3 events every 2 minutes, 10 every hour
+// and 30 every day. It uses the same algorithm as the RateLimiter, but sharing
+// a single history of past events across all the tiers instead of keeping one
+// per tier. The timestamps of the events must also be sent in ascending order.
+// The zero value for TieredRateLimiter is one with no tiers, which allows all
+// events.
+type TieredRateLimiter struct {
+	// Tiers specifies all the limits that must be respected by the events. An
+	// event is only allowed if it is allowed by every tier.
+	Tiers []RateTier
+
+	pastEvents list.List
+}
+
+// Exceeded checks which tier would be exceeded by the given event, without
+// making any changes to the internal state of the rate limiter. It returns the
+// index of the first exceeded tier in the Tiers slice, or -1 if the event is
+// allowed by all of them.
+func (l *TieredRateLimiter) Exceeded(event time.Time) int {
+	for i, tier := range l.Tiers {
+		if l.countEventsAfter(event.Add(-tier.Interval)) >= tier.MaxEvents {
+			return i
+		}
+	}
+	return -1
+}
+
+// Allows function checks whether the given event is allowed to happen by all
+// the tiers, without making any changes to the internal state of the limiter.
+func (l *TieredRateLimiter) Allows(event time.Time) bool {
+	return l.Exceeded(event) < 0
+}
+
+// Take actually updates the internal state of the rate limiter in order to
+// consider the given event as having happened. It still checks if the event is
+// actually allowed and thus returns whether the event was taken or not, with
+// the same guarantees of consistency with Allows as the RateLimiter.
+func (l *TieredRateLimiter) Take(event time.Time) bool {
+	l.popEventsNotAfter(event.Add(-l.maxInterval()))
+	if !l.Allows(event) {
+		return false
+	}
+	l.pastEvents.PushBack(event)
+	return true
+}
+
+// RetryAfter returns the earliest time, not before the given event, at which an
+// event would be allowed by all the tiers considering the past events already
+// taken. It returns false if no event will ever be allowed.
+func (l *TieredRateLimiter) RetryAfter(event time.Time) (time.Time, bool) {
+	retryAfter := event
+	for _, tier := range l.Tiers {
+		if tier.MaxEvents <= 0 {
+			return time.Time{}, false
+		}
+		threshold := event.Add(-tier.Interval)
+		excess := l.countEventsAfter(threshold) - tier.MaxEvents
+		if excess < 0 {
+			continue
+		}
+
+		// the oldest events in the interval must leave it, up to the excess one
+		elm := l.pastEvents.Front()
+		for !elm.Value.(time.Time).After(threshold) {
+			elm = elm.Next()
+		}
+		for i := 0; i < excess; i++ {
+			elm = elm.Next()
+		}
+		if retry := elm.Value.(time.Time).Add(tier.Interval); retry.After(retryAfter) {
+			retryAfter = retry
+		}
+	}
+	return retryAfter, true
+}
+
+func (l *TieredRateLimiter) maxInterval() time.Duration {
+	max := time.Duration(0)
+	for _, tier := range l.Tiers {
+		if tier.Interval > max {
+			max = tier.Interval
+		}
+	}
+	return max
+}
+
+func (l *TieredRateLimiter) popEventsNotAfter(threshold time.Time) {
+	for l.pastEvents.Len() > 0 {
+		elm := l.pastEvents.Front()
+		value := elm.Value.(time.Time)
+		if value.After(threshold) {
+			break
+		}
+		l.pastEvents.Remove(elm)
+	}
+}
+
+func (l *TieredRateLimiter) countEventsAfter(threshold time.Time) int {
+	count := 0
+	for elm := l.pastEvents.Back(); elm != nil; elm = elm.Prev() {
+		value := elm.Value.(time.Time)
+		if !value.After(threshold) {
+			break
+		}
+		count++
+	}
+	return count
+}
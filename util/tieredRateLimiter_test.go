@@ -0,0 +1,85 @@
+package util_test
+
+import (
+	"nuledger/util"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTieredRateLimiter(t *testing.T) {
+	Convey("Given a TieredRateLimiter", t, func() {
+		limiter := util.TieredRateLimiter{Tiers: []util.RateTier{
+			{MaxEvents: 3, Interval: 2 * time.Minute},
+			{MaxEvents: 5, Interval: 1 * time.Hour},
+		}}
+
+		testTake := func(time time.Time) bool {
+			allows := limiter.Allows(time)
+			taken := limiter.Take(time)
+			So(allows, ShouldEqual, taken)
+			return allows
+		}
+
+		Convey("Allows should not affect state", func() {
+			for i := 0; i < 10; i++ {
+				So(limiter.Allows(startTime), ShouldBeTrue)
+			}
+		})
+
+		Convey("When the quota of the first tier is consumed", func() {
+			for i := 0; i < 3; i++ {
+				So(testTake(startTime), ShouldBeTrue)
+			}
+
+			Convey("It should report the first tier as exceeded", func() {
+				So(limiter.Exceeded(startTime.Add(1*time.Minute)), ShouldEqual, 0)
+				So(testTake(startTime.Add(1*time.Minute)), ShouldBeFalse)
+			})
+			Convey("It should retry after the first interval passes", func() {
+				retryAfter, ok := limiter.RetryAfter(startTime.Add(1 * time.Minute))
+				So(ok, ShouldBeTrue)
+				So(retryAfter, ShouldEqual, startTime.Add(2*time.Minute))
+			})
+
+			Convey("And the quota of the second tier is consumed", func() {
+				So(testTake(startTime.Add(2*time.Minute)), ShouldBeTrue)
+				So(testTake(startTime.Add(4*time.Minute)), ShouldBeTrue)
+
+				Convey("It should report the second tier as exceeded", func() {
+					So(limiter.Exceeded(startTime.Add(10*time.Minute)), ShouldEqual, 1)
+					So(testTake(startTime.Add(1*time.Hour-1)), ShouldBeFalse)
+				})
+				Convey("It should retry after the longest interval passes", func() {
+					retryAfter, ok := limiter.RetryAfter(startTime.Add(10 * time.Minute))
+					So(ok, ShouldBeTrue)
+					So(retryAfter, ShouldEqual, startTime.Add(1*time.Hour))
+				})
+				Convey("It should allow events once the longest interval passes", func() {
+					So(limiter.Exceeded(startTime.Add(1*time.Hour)), ShouldEqual, -1)
+					So(testTake(startTime.Add(1*time.Hour)), ShouldBeTrue)
+				})
+			})
+		})
+
+		Convey("It should allow retrying right away when no tier is exceeded", func() {
+			retryAfter, ok := limiter.RetryAfter(startTime)
+			So(ok, ShouldBeTrue)
+			So(retryAfter, ShouldEqual, startTime)
+		})
+
+		Convey("It should never allow retrying with a tier of no events", func() {
+			limiter.Tiers = append(limiter.Tiers, util.RateTier{MaxEvents: 0, Interval: time.Minute})
+			_, ok := limiter.RetryAfter(startTime)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Its zero value should take all events", func() {
+			limiter = util.TieredRateLimiter{}
+			for i := 0; i < 10; i++ {
+				So(testTake(startTime), ShouldBeTrue)
+			}
+		})
+	})
+}